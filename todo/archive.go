@@ -0,0 +1,53 @@
+package todo
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveFile returns the name of the archive kept next to the
+// ToDo file filename, e.g. .todo.archive.json for .todo.json
+func ArchiveFile(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + ".archive" + ext
+}
+
+// Archive removes from the list the items completed before the
+// given time and adds them to the archive a, and returns how many
+// items were archived. The IDs of the archive aren't given again,
+// and an item with an ID already in the archive, only found in
+// files written before IDs were kept for good, gets a new one
+func (l *List) Archive(a *List, before time.Time) int {
+	old := map[int]bool{}
+	for _, t := range *l {
+		if t.Done && !t.trashed() && t.CompletedAt.Before(before) {
+			old[t.ID] = true
+		}
+	}
+	if len(old) == 0 {
+		return 0
+	}
+
+	// new items get IDs past the ones of the archive
+	next := l.nextID()
+	if n := a.nextID(); n > next {
+		next = n
+	}
+	for _, t := range *l {
+		if !old[t.ID] {
+			continue
+		}
+		if _, err := a.index(t.ID); err == nil {
+			t.ID = next
+			next++
+		}
+		*a = append(*a, t)
+	}
+
+	for id := range old {
+		l.Delete(id)
+	}
+	l.keepIDs(next)
+	return len(old)
+}
@@ -0,0 +1,60 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"testing"
+	"time"
+)
+
+// TestArchive tests moving old completed items to an archive
+func TestArchive(t *testing.T) {
+	l := todo.List{}
+	l.Add("old\nrecent\npending")
+	for _, id := range []int{1, 2} {
+		if err := l.Complete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l[0].CompletedAt = time.Now().AddDate(0, 0, -40)
+
+	// the archive already holds another item 1, as in files written
+	// before IDs were kept for good
+	a := todo.List{l[0]}
+	a[0].Task = "old copy"
+
+	if n := l.Archive(&a, time.Now().AddDate(0, 0, -30)); n != 1 {
+		t.Fatalf("Expected %d item archived, got %d instead", 1, n)
+	}
+
+	expected := "X 2: recent\n  3: pending\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+	if out := a.String(); out != "X 1: old copy\nX 4: old\n" {
+		t.Errorf("Expected the archive to keep both items, got %q", out)
+	}
+
+	// new items don't reuse the IDs of the archive
+	l.Add("new")
+	if id := l[len(l)-1].ID; id != 5 {
+		t.Errorf("Expected new item ID %d, got %d instead", 5, id)
+	}
+
+	if n := l.Archive(&a, time.Now().AddDate(0, 0, -30)); n != 0 {
+		t.Errorf("Expected nothing to archive, got %d", n)
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	testCases := map[string]string{
+		".todo.json":   ".todo.archive.json",
+		"dir/todo.txt": "dir/todo.archive.txt",
+		"tasks":        "tasks.archive",
+	}
+
+	for name, expected := range testCases {
+		if a := todo.ArchiveFile(name); a != expected {
+			t.Errorf("Expected %q for %q, got %q instead", expected, name, a)
+		}
+	}
+}
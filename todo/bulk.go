@@ -0,0 +1,183 @@
+package todo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// idRanges matches lists of IDs and ranges of IDs, e.g. 1-5,8,12
+var idRanges = regexp.MustCompile(`^\s*\d+(\s*-\s*\d+)?(\s*,\s*\d+(\s*-\s*\d+)?)*\s*$`)
+
+// maxMissing is the number of missing IDs named in errors
+const maxMissing = 5
+
+// BulkResult is the summary of a bulk operation
+type BulkResult struct {
+	// Changed are the IDs of the items changed
+	Changed []int
+	// Skipped are the IDs of the items already in the state asked
+	// for, left as they were
+	Skipped []int
+}
+
+// IsIDList reports whether s selects items by ID rather than with
+// a query, see Select
+func IsIDList(s string) bool {
+	return idRanges.MatchString(s)
+}
+
+// Select returns the IDs of the items selected by s: either a list
+// of IDs and ranges of IDs, e.g. "1-5,8,12", or a query, see
+// ParseQuery. A range selects the items it holds, skipping the IDs
+// of deleted items and items in the trash, but an ID given on its
+// own must be an item out of the trash. The selection must hold
+// some items, so it can be applied as a whole
+func (l *List) Select(s string) ([]int, error) {
+	if !IsIDList(s) {
+		f, err := ParseQuery(s)
+		if err != nil {
+			return nil, err
+		}
+		ids := []int{}
+		for _, k := range l.view(f, ByIndex) {
+			ids = append(ids, (*l)[k].ID)
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("No items match %q", s)
+		}
+		return ids, nil
+	}
+
+	exists := map[int]bool{}
+	for _, t := range *l {
+		if !t.hidden() {
+			exists[t.ID] = true
+		}
+	}
+	last := l.nextID() - 1
+
+	ids, missing := []int{}, []string{}
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		from, _ := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if len(bounds) == 1 {
+			switch {
+			case !exists[from]:
+				missing = append(missing, strconv.Itoa(from))
+			case !seen[from]:
+				seen[from] = true
+				ids = append(ids, from)
+			}
+			continue
+		}
+
+		to, _ := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if to < from {
+			return nil, fmt.Errorf("Invalid range %q", strings.TrimSpace(part))
+		}
+		// IDs past the last one can't exist, don't go through them
+		if to > last {
+			to = last
+		}
+		for id := from; id <= to; id++ {
+			if exists[id] && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	switch {
+	case len(missing) == 1:
+		return nil, fmt.Errorf("Item %s doesn't exist", missing[0])
+	case len(missing) > maxMissing:
+		return nil, fmt.Errorf("Items %s, ... don't exist", strings.Join(missing[:maxMissing], ", "))
+	case len(missing) > 0:
+		return nil, fmt.Errorf("Items %s don't exist", strings.Join(missing, ", "))
+	case len(ids) == 0:
+		return nil, fmt.Errorf("No items in %q", strings.TrimSpace(s))
+	}
+	return ids, nil
+}
+
+// Bulk applies op to every item of ids as one transaction: when op
+// fails for any item, the list is left unchanged. op reports whether
+// it changed the item. Items op fails for are tried again after the
+// others, so e.g. completing a parent along with its subtasks works
+// in any order
+func (l *List) Bulk(ids []int, op func(l *List, id int) (bool, error)) (BulkResult, error) {
+	// work on a copy, see List
+	c := append(List{}, *l...)
+	r := BulkResult{Changed: []int{}, Skipped: []int{}}
+
+	pending := ids
+	for len(pending) > 0 {
+		failed := []int{}
+		var err error
+		for _, id := range pending {
+			changed, opErr := op(&c, id)
+			switch {
+			case opErr != nil:
+				failed = append(failed, id)
+				if err == nil {
+					err = opErr
+				}
+			case changed:
+				r.Changed = append(r.Changed, id)
+			default:
+				r.Skipped = append(r.Skipped, id)
+			}
+		}
+		if len(failed) == len(pending) {
+			return BulkResult{}, err
+		}
+		pending = failed
+	}
+
+	*l = c
+	return r, nil
+}
+
+// CompleteAll completes the items of ids in one transaction, along
+// with their open subtasks when subtasks is set. Completed items
+// are skipped
+func (l *List) CompleteAll(ids []int, subtasks bool) (BulkResult, error) {
+	return l.Bulk(ids, func(l *List, id int) (bool, error) {
+		k, err := l.index(id)
+		if err != nil {
+			return false, err
+		}
+		if (*l)[k].Done {
+			return false, nil
+		}
+		if subtasks {
+			return true, l.CompleteWithSubtasks(id)
+		}
+		return true, l.Complete(id)
+	})
+}
+
+// UncompleteAll marks the items of ids as pending again in one
+// transaction. Pending items are skipped
+func (l *List) UncompleteAll(ids []int) (BulkResult, error) {
+	return l.Bulk(ids, func(l *List, id int) (bool, error) {
+		k, err := l.index(id)
+		if err != nil {
+			return false, err
+		}
+		if !(*l)[k].Done {
+			return false, nil
+		}
+		return true, l.Uncomplete(id)
+	})
+}
+
+// TrashAll moves the items of ids to the trash in one transaction
+func (l *List) TrashAll(ids []int) (BulkResult, error) {
+	return l.Bulk(ids, func(l *List, id int) (bool, error) {
+		return true, l.Trash(id)
+	})
+}
@@ -0,0 +1,123 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"reflect"
+	"testing"
+)
+
+// TestSelect tests selecting items by IDs, ranges and queries
+func TestSelect(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1 +home\ntask 2\ntask 3 +home\ntask 4\ntask 5\ntask 6")
+	if err := l.Trash(4); err != nil {
+		t.Fatal(err)
+	}
+	l.Add("task 7")
+	if err := l.Delete(7); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		sel      string
+		expected []int
+		err      string
+	}{
+		{name: "Single", sel: "2", expected: []int{2}},
+		{name: "List", sel: "5,1,3", expected: []int{5, 1, 3}},
+		{name: "Ranges", sel: "1-3, 5-6", expected: []int{1, 2, 3, 5, 6}},
+		{name: "Duplicates", sel: "1-3,2", expected: []int{1, 2, 3}},
+		{name: "Query", sel: "project:home", expected: []int{1, 3}},
+		{name: "Missing", sel: "1,9", err: "Item 9 doesn't exist"},
+		{name: "ManyMissing", sel: "7,2,8,4", err: "Items 7, 8, 4 don't exist"},
+		{name: "RangeSkipsTrashed", sel: "3-5", expected: []int{3, 5}},
+		{name: "RangeSkipsDeleted", sel: "1-2,6-8", expected: []int{1, 2, 6}},
+		{name: "PastLast", sel: "5-1000000", expected: []int{5, 6}},
+		{name: "EmptyRange", sel: "4-4, 7-9", err: `No items in "4-4, 7-9"`},
+		{name: "Trashed", sel: "4", err: "Item 4 doesn't exist"},
+		{name: "Backwards", sel: "3-1", err: `Invalid range "3-1"`},
+		{name: "NoMatch", sel: "project:work", err: `No items match "project:work"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids, err := l.Select(tc.sel)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, got %v instead", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("Expected %v, got %v instead", tc.expected, ids)
+			}
+		})
+	}
+}
+
+// TestBulk tests that bulk operations change all the items or none
+func TestBulk(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3\ntask 4")
+	if err := l.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(4); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parent is completed once its subtask is, whatever the order
+	r, err := l.CompleteAll([]int{1, 2, 4}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := todo.BulkResult{Changed: []int{2, 1}, Skipped: []int{4}}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("Expected %+v, got %+v instead", expected, r)
+	}
+	if !l[0].Done || !l[1].Done || l[2].Done {
+		t.Errorf("Expected items 1 and 2 completed, got %q", l.String())
+	}
+
+	// a failing item leaves the whole list unchanged
+	l.Add("task 5")
+	if err := l.SetParent(5, 3); err != nil {
+		t.Fatal(err)
+	}
+	before := append(todo.List{}, l...)
+	if _, err := l.CompleteAll([]int{1, 3}, false); err == nil {
+		t.Fatal("Expected error completing an item with open subtasks")
+	}
+	if !reflect.DeepEqual(l, before) {
+		t.Errorf("Expected the list unchanged, got %q", l.String())
+	}
+	if _, err := l.CompleteAll([]int{3}, true); err != nil {
+		t.Fatal(err)
+	}
+	if !l[4].Done {
+		t.Errorf("Expected the subtask completed with -subtasks")
+	}
+
+	r, err = l.UncompleteAll([]int{2, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Changed, []int{2, 4}) || l[0].Done {
+		t.Errorf("Expected items 2, 4 and their parent pending, got %+v", r)
+	}
+
+	if _, err := l.TrashAll([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.TrashAll([]int{3, 2}); err == nil {
+		t.Fatal("Expected error trashing an item twice")
+	}
+	expected2 := "X 3: task 3\nX   5: task 5\n  4: task 4\n"
+	if out := l.String(); out != expected2 {
+		t.Errorf("Expected %q, got %q instead", expected2, out)
+	}
+}
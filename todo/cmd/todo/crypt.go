@@ -0,0 +1,125 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// newStore returns the store of the ToDo file, encrypted with the
+// key of the configuration when there is one
+func newStore(filename string, c config) (todo.Store, error) {
+	return todo.NewStoreWithKey(filename, c.store, c.key)
+}
+
+// openJournal opens the journal of a ToDo file, encrypted with the
+// key of the configuration when there is one
+func openJournal(filename string, c config) (*todo.Journal, error) {
+	return todo.OpenJournalWithKey(filename, c.key)
+}
+
+// keyFromEnv returns the key given in the environment, as the path
+// of a key file in fileEnv or as a passphrase in passEnv, or nil if
+// neither is set
+func keyFromEnv(passEnv, fileEnv string) (*todo.Key, error) {
+	passphrase, keyFile := os.Getenv(passEnv), os.Getenv(fileEnv)
+	switch {
+	case passphrase != "" && keyFile != "":
+		return nil, fmt.Errorf("Set either %s or %s, not both", passEnv, fileEnv)
+	case keyFile != "":
+		return todo.ReadKeyFile(keyFile)
+	case passphrase != "":
+		return todo.NewPassphraseKey(passphrase)
+	}
+	return nil, nil
+}
+
+// rekey reads the ToDo file, its archive, backup and journal with
+// the key from and writes them again with the key to, a nil key
+// meaning plain text. It encrypts, decrypts and rotates the key
+// of the files depending on the keys given. It's the only place
+// plain files and journal lines are read with a key, as they are
+// what encrypting starts from
+func rekey(filename string, c config, from, to *todo.Key, out io.Writer) error {
+	lock, err := todo.LockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// the archive and backup are only rewritten when they exist
+	files := []string{filename}
+	for _, f := range []string{todo.ArchiveFile(filename), filename + ".bak"} {
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+
+	read := from.AllowPlain()
+	for _, f := range files {
+		src, err := todo.NewStoreWithKey(f, c.store, read)
+		if err != nil {
+			return err
+		}
+		dst, err := todo.NewStoreWithKey(f, c.store, to)
+		if err != nil {
+			return err
+		}
+
+		l := &todo.List{}
+		if err := src.Load(l); err != nil {
+			return err
+		}
+		if err := dst.Save(l); err != nil {
+			return err
+		}
+	}
+
+	j, err := todo.OpenJournalWithKey(filename+".journal", read)
+	if err != nil {
+		return err
+	}
+	if err := j.Rekey(to); err != nil {
+		return err
+	}
+
+	switch {
+	case to == nil:
+		fmt.Fprintf(out, "Decrypted %s\n", filename)
+	case from == to:
+		fmt.Fprintf(out, "Encrypted %s\n", filename)
+	default:
+		fmt.Fprintf(out, "Changed the key of %s\n", filename)
+	}
+	return nil
+}
+
+// runKeys executes the commands encrypting and decrypting the ToDo
+// file and changing its key
+func runKeys(filename string, c config, out io.Writer) error {
+	switch {
+	case c.genKey != "":
+		if _, err := todo.WriteKeyFile(c.genKey); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Wrote a new key to %s\n", c.genKey)
+		return nil
+	case c.key == nil:
+		return errors.New("Missing key, set TODO_PASSPHRASE or TODO_KEYFILE")
+	case c.encrypt:
+		return rekey(filename, c, c.key, c.key, out)
+	case c.decrypt:
+		return rekey(filename, c, c.key, nil, out)
+	}
+
+	to, err := keyFromEnv("TODO_NEW_PASSPHRASE", "TODO_NEW_KEYFILE")
+	if err != nil {
+		return err
+	}
+	if to == nil {
+		return errors.New("Missing new key, set TODO_NEW_PASSPHRASE or TODO_NEW_KEYFILE")
+	}
+	return rekey(filename, c, c.key, to, out)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// listsDir returns the directory keeping the named lists of the
+// ToDo file filename, TODO_DIR when it's set
+func listsDir(filename, env string) string {
+	if env != "" {
+		return env
+	}
+	return filepath.Join(filepath.Dir(filename), ".todo.lists")
+}
+
+// listFile returns the file of the list selected with -L
+func listFile(ws *todo.Workspace, name string) (string, error) {
+	if !ws.Exists(name) {
+		return "", fmt.Errorf("List %s doesn't exist, create it with -create-list", name)
+	}
+	return ws.File(name)
+}
+
+// runLists executes the commands working on the lists of the
+// workspace rather than on the items of one list
+func runLists(ws *todo.Workspace, c config, out io.Writer) error {
+	switch {
+	case c.lists:
+		return printLists(ws, c, out)
+	case c.createList != "":
+		if err := ws.Create(c.createList); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created list %s\n", c.createList)
+	case c.renameList != "":
+		names := strings.SplitN(c.renameList, ",", 2)
+		if len(names) != 2 {
+			return fmt.Errorf("Invalid lists %q, expected OLD,NEW", c.renameList)
+		}
+		if err := ws.Rename(names[0], names[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Renamed list %s to %s\n", names[0], names[1])
+	case c.deleteList != "":
+		if err := ws.Delete(c.deleteList); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Deleted list %s\n", c.deleteList)
+	case c.toList != "":
+		return moveToList(ws, c, out)
+	case c.all:
+		return showAll(ws, c, out)
+	default:
+		return errors.New("Invalid option")
+	}
+
+	return nil
+}
+
+// printLists prints the name of every list with its number of
+// pending and total tasks
+func printLists(ws *todo.Workspace, c config, out io.Writer) error {
+	names, err := ws.Names()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		file, err := ws.File(name)
+		if err != nil {
+			return err
+		}
+		l, err := load(file, c)
+		if err != nil {
+			return err
+		}
+
+		total, pending := 0, 0
+		for _, t := range *l {
+			if t.Removed || t.DeletedAt != nil {
+				continue
+			}
+			total++
+			if !t.Done {
+				pending++
+			}
+		}
+		fmt.Fprintf(out, "%s: %d pending, %d total\n", name, pending, total)
+	}
+
+	return nil
+}
+
+// moveToList moves the item given with -move from the list given
+// with -L to the list given with -to-list. Both files are locked in
+// the order of their names, so two moves in opposite directions
+// can't wait on each other, and the target is saved first, so the
+// item is never missing from both
+func moveToList(ws *todo.Workspace, c config, out io.Writer) error {
+	if c.move == 0 {
+		return errors.New("Missing item to move, use -move ID with -to-list")
+	}
+	from, err := listFile(ws, c.listName)
+	if err != nil {
+		return err
+	}
+	to, err := listFile(ws, c.toList)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("Item %d is already in list %s", c.move, c.toList)
+	}
+
+	files := []string{from, to}
+	if to < from {
+		files = []string{to, from}
+	}
+	for _, f := range files {
+		lock, err := todo.LockFile(f)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	src, err := openList(from, c)
+	if err != nil {
+		return err
+	}
+	dst, err := openList(to, c)
+	if err != nil {
+		return err
+	}
+
+	id, err := src.l.MoveTo(c.move, dst.l)
+	if err != nil {
+		return err
+	}
+	if err := dst.save("move-to", c); err != nil {
+		return err
+	}
+	if err := src.save("move-to", c); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Moved item %d to list %s as item %d\n", c.move, c.toList, id)
+	return nil
+}
+
+// openedList is a list read from its file, to be saved along with
+// its journal once changed
+type openedList struct {
+	file   string
+	store  todo.Store
+	l      *todo.List
+	before todo.List
+	j      *todo.Journal
+}
+
+// openList reads the list in file, which must be locked already
+func openList(file string, c config) (*openedList, error) {
+	store, err := newStore(file, c)
+	if err != nil {
+		return nil, err
+	}
+	l := &todo.List{}
+	if err := store.Load(l); err != nil {
+		return nil, err
+	}
+	j, err := openJournal(file+".journal", c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openedList{file: file, store: store, l: l, before: append(todo.List{}, *l...), j: j}, nil
+}
+
+// save saves the list and records the change in its journal as op
+func (o *openedList) save(op string, c config) error {
+	o.j.Record(op, o.before, *o.l)
+
+	if c.backup {
+		if err := todo.Backup(o.file); err != nil {
+			return err
+		}
+	}
+	if err := o.store.Save(o.l); err != nil {
+		return err
+	}
+	return o.j.Save()
+}
+
+// showAll prints the selected tasks of every list, each under the
+// name of its list. As JSON, the lists are an object keyed by name
+func showAll(ws *todo.Workspace, c config, out io.Writer) error {
+	names, err := ws.Names()
+	if err != nil {
+		return err
+	}
+	order, err := todo.ParseOrder(c.sortBy)
+	if err != nil {
+		return err
+	}
+	layout, err := todo.ParseLayout(layoutName(c))
+	if err != nil {
+		return err
+	}
+	isJSON := layoutName(c) == todo.LayoutJSON
+
+	all := map[string]json.RawMessage{}
+	for k, name := range names {
+		file, err := ws.File(name)
+		if err != nil {
+			return err
+		}
+		l, err := load(file, c)
+		if err != nil {
+			return err
+		}
+		filter, err := viewFilter(l, c, time.Now())
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := l.Render(&buf, layout, filter, order); err != nil {
+			return err
+		}
+		if isJSON {
+			all[name] = buf.Bytes()
+			continue
+		}
+
+		if k > 0 {
+			fmt.Fprintln(out)
+		}
+		if layoutName(c) == todo.LayoutMarkdown {
+			fmt.Fprintf(out, "## %s\n\n", name)
+		} else {
+			fmt.Fprintf(out, "%s:\n", name)
+		}
+		out.Write(buf.Bytes())
+	}
+
+	if isJSON {
+		js, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(js))
+	}
+	return nil
+}
@@ -3,93 +3,586 @@ package main
 import (
 	"bufio"
 	"cli_tools/todo"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var todoFileName = ".todo.json"
 
+// config holds the options given on the command line
+type config struct {
+	add         bool
+	list        bool
+	complete    string
+	delete      string
+	verbose     bool
+	m           bool
+	u           bool
+	edit        int
+	reopen      string
+	move        int
+	to          int
+	swap        string
+	restore     int
+	empty       bool
+	trash       bool
+	archive     int
+	archived    bool
+	start       int
+	stop        bool
+	timeBy      string
+	csv         bool
+	report      bool
+	since       string
+	until       string
+	by          string
+	annotate    int
+	editor      bool
+	priority    string
+	due         string
+	recur       string
+	parent      string
+	block       string
+	unblock     string
+	subtasks    bool
+	next        bool
+	sortBy      string
+	overdue     bool
+	week        bool
+	tag         string
+	tags        bool
+	query       string
+	json        bool
+	format      string
+	undo        int
+	redo        int
+	history     bool
+	store       string
+	imp         string
+	exp         string
+	ics         string
+	icsDir      string
+	backup      bool
+	serve       bool
+	tui         bool
+	addr        string
+	listName    string
+	lists       bool
+	createList  string
+	renameList  string
+	deleteList  string
+	toList      string
+	all         bool
+	merge       bool
+	mergeDriver bool
+	remind      bool
+	remindAt    string
+	notify      string
+	notifyCmd   string
+	snooze      int
+	snoozeFor   time.Duration
+	key         *todo.Key
+	encrypt     bool
+	decrypt     bool
+	rotateKey   bool
+	genKey      string
+	token       string
+	args        []string
+}
+
 func main() {
+	c := config{}
+
 	// parsing command-line flags
-	add := flag.Bool("add", false, "Task to be included in ToDo list")
-	list := flag.Bool("list", false, "List all tasks")
-	complete := flag.Int("complete", 0, "Item to be completed")
-	delete := flag.Int("delete", 0, "Item to delete (doesn't matter if task is completed or not)")
-	verbose := flag.Bool("verbose", false, "Show verbose output")
-	m := flag.Bool("m", false, "Do multiline input from STDIN")
-	u := flag.Bool("u", false, "Show uncomplete tasks only")
+	flag.BoolVar(&c.add, "add", false, "Task to be included in ToDo list")
+	flag.BoolVar(&c.list, "list", false, "List all tasks")
+	flag.StringVar(&c.complete, "complete", "", "IDs of the items to be completed, e.g. 1-5,8,12, or a query selecting them")
+	flag.StringVar(&c.delete, "delete", "", "IDs of the items to move to the trash, e.g. 1-5,8,12, or a query selecting them (doesn't matter if task is completed or not)")
+	flag.IntVar(&c.restore, "restore", 0, "ID of the item to take out of the trash")
+	flag.BoolVar(&c.empty, "empty-trash", false, "Delete the items in the trash for good")
+	flag.BoolVar(&c.trash, "trash", false, "List the items in the trash")
+	flag.IntVar(&c.archive, "archive", 0, "Move the tasks completed more than DAYS days ago to the archive file")
+	flag.BoolVar(&c.archived, "archived", false, "Include the archived tasks in the listed tasks")
+	flag.BoolVar(&c.verbose, "verbose", false, "Show verbose output, same as -format verbose")
+	flag.BoolVar(&c.m, "m", false, "Do multiline input from STDIN")
+	flag.BoolVar(&c.u, "u", false, "Show uncomplete tasks only")
+	flag.IntVar(&c.edit, "edit", 0, "ID of the item to edit, the arguments replace its text; use with -p, -due, -recur, -parent, -block and -unblock")
+	flag.StringVar(&c.reopen, "uncomplete", "", "IDs of the completed items to mark as pending again, e.g. 1-5,8,12, or a query selecting them")
+	flag.IntVar(&c.move, "move", 0, "ID of the item to move to the position given with -to")
+	flag.IntVar(&c.to, "to", 0, "Position (from 1) to move the item of -move to")
+	flag.StringVar(&c.swap, "swap", "", "Two comma-separated IDs of items to swap")
+	flag.IntVar(&c.annotate, "annotate", 0, "ID of the item to add a note to, the note is read from the arguments or STDIN")
+	flag.BoolVar(&c.editor, "editor", false, "Write the note of -annotate in $VISUAL or $EDITOR")
+	flag.StringVar(&c.priority, "p", "", "Priority (A-Z) of the added or edited task, '-' clears it")
+	flag.StringVar(&c.due, "due", "", "Due date (YYYY-MM-DD) of the added or edited task, '-' clears it")
+	flag.StringVar(&c.recur, "recur", "", "Repeat the added or edited task: daily, weekly[:mon,...], monthly:DAY or every:DAYS, '-' stops it")
+	flag.StringVar(&c.parent, "parent", "", "Make the added or edited task a subtask of this ID, '-' makes it top-level")
+	flag.StringVar(&c.block, "block", "", "Comma-separated IDs blocking the added or edited task")
+	flag.StringVar(&c.unblock, "unblock", "", "Comma-separated IDs not blocking the edited task anymore")
+	flag.BoolVar(&c.subtasks, "subtasks", false, "Complete the open subtasks of the completed item too")
+	flag.BoolVar(&c.next, "next", false, "Show tasks which aren't blocked only")
+	flag.StringVar(&c.sortBy, "sort", "", "Sort listed tasks by 'priority' or 'due'")
+	flag.BoolVar(&c.overdue, "overdue", false, "Show overdue tasks only")
+	flag.BoolVar(&c.week, "week", false, "Show tasks due this week only")
+	flag.StringVar(&c.tag, "tag", "", "Show tasks matching a tag expression, e.g. \"+work !@phone, +home\"")
+	flag.IntVar(&c.start, "start", 0, "ID of the item to start tracking time on, stopping the one started before")
+	flag.BoolVar(&c.stop, "stop", false, "Stop tracking time on the started item")
+	flag.StringVar(&c.timeBy, "time-report", "", "Show the time tracked on the listed tasks per 'day', 'week' or 'tag'")
+	flag.BoolVar(&c.csv, "csv", false, "Write the time report as CSV")
+	flag.BoolVar(&c.report, "report", false, "Show statistics of the listed tasks, use with -since, -until, -by and -json")
+	flag.StringVar(&c.since, "since", "", "First day (YYYY-MM-DD) of the report, 30 days before -until by default")
+	flag.StringVar(&c.until, "until", "", "Last day (YYYY-MM-DD) of the report, today by default")
+	flag.StringVar(&c.by, "by", "day", "Count the tasks of the report per 'day' or 'week'")
+	flag.BoolVar(&c.tags, "tags", false, "Show the number of tasks per tag")
+	flag.StringVar(&c.query, "q", "", "Show tasks matching a query, e.g. 'done:false and text~\"deploy\"'")
+	flag.BoolVar(&c.json, "json", false, "Show the listed tasks as JSON, same as -format json")
+	flag.StringVar(&c.format, "format", "", "Show the listed tasks as 'text', 'verbose', 'json', 'csv', 'markdown' or with a Go template, e.g. '{{.ID}} {{.Task}}'")
+	flag.IntVar(&c.undo, "undo", 0, "Undo the last N operations")
+	flag.IntVar(&c.redo, "redo", 0, "Redo the last N undone operations")
+	flag.BoolVar(&c.history, "history", false, "Show the history of operations")
+	flag.StringVar(&c.imp, "import", "", "Add the tasks of a todo.txt file ('-' for STDIN)")
+	flag.StringVar(&c.exp, "export", "", "Write the tasks to a todo.txt file ('-' for STDOUT)")
+	flag.StringVar(&c.ics, "ics", "", "Write the tasks to an iCalendar file ('-' for STDOUT)")
+	flag.StringVar(&c.icsDir, "ics-dir", "", "Write every task to its own iCalendar file in a directory")
+	flag.BoolVar(&c.tui, "i", false, "Browse and change the tasks in an interactive full-screen mode")
+	flag.BoolVar(&c.serve, "serve", false, "Serve the tasks over a JSON API, set TODO_TOKEN to require a bearer token")
+	flag.StringVar(&c.addr, "addr", "localhost:8080", "Address the JSON API listens on")
+	flag.StringVar(&c.listName, "L", todo.DefaultList, "Name of the list to work on, set TODO_DIR to keep the lists elsewhere than .todo.lists")
+	flag.BoolVar(&c.lists, "lists", false, "Show the lists with their number of tasks")
+	flag.StringVar(&c.createList, "create-list", "", "Name of the list to create")
+	flag.StringVar(&c.renameList, "rename-list", "", "Old and new comma-separated names of the list to rename")
+	flag.StringVar(&c.deleteList, "delete-list", "", "Name of the empty list to delete")
+	flag.StringVar(&c.toList, "to-list", "", "Name of the list to move the item of -move to")
+	flag.BoolVar(&c.all, "all", false, "Show the listed tasks of every list")
+	flag.BoolVar(&c.merge, "merge", false, "Merge the changes made to the BASE file in the OURS and THEIRS files given as arguments into OURS")
+	flag.BoolVar(&c.mergeDriver, "merge-driver", false, "Merge like -merge as a git merge driver: todo -merge-driver %O %A %B")
+	flag.BoolVar(&c.remind, "remind", false, "Watch the ToDo file and notify the tasks coming due until interrupted")
+	flag.StringVar(&c.remindAt, "remind-at", "09:00", "Time of day (HH:MM) the tasks due that day are notified")
+	flag.StringVar(&c.notify, "notify", "stdout", "Notify the reminders on 'stdout', with a 'command' or as 'desktop' notifications")
+	flag.StringVar(&c.notifyCmd, "notify-cmd", "", "Shell command run for every reminder with -notify command, given TODO_ID, TODO_TASK, TODO_DUE and TODO_MESSAGE")
+	flag.IntVar(&c.snooze, "snooze", 0, "ID of the item whose reminder to postpone by -snooze-for")
+	flag.DurationVar(&c.snoozeFor, "snooze-for", time.Hour, "How long to snooze the reminder of -snooze, e.g. 30m or 2h")
+	flag.BoolVar(&c.encrypt, "encrypt", false, "Encrypt the ToDo file, its archive and journal with TODO_PASSPHRASE or TODO_KEYFILE")
+	flag.BoolVar(&c.decrypt, "decrypt", false, "Decrypt the ToDo file, its archive and journal for good")
+	flag.BoolVar(&c.rotateKey, "rotate-key", false, "Encrypt the ToDo file again with TODO_NEW_PASSPHRASE or TODO_NEW_KEYFILE")
+	flag.StringVar(&c.genKey, "gen-key", "", "Write a new random key to a key file, to be used with TODO_KEYFILE")
+	flag.StringVar(&c.store, "store", "", "Storage format of the ToDo file: json, txt or db (default from the file extension)")
 	flag.Parse()
+	c.args = flag.Args()
 
 	if os.Getenv("TODO_FILENAME") != "" {
 		todoFileName = os.Getenv("TODO_FILENAME")
 	}
+	// keep a copy of the previous version of the file on every save
+	c.backup = os.Getenv("TODO_BACKUP") != ""
+	// bearer token required by the JSON API, if any
+	c.token = os.Getenv("TODO_TOKEN")
 
-	l := &todo.List{}
+	// the files are encrypted with the passphrase or key file given
+	key, err := keyFromEnv("TODO_PASSPHRASE", "TODO_KEYFILE")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c.key = key
+
+	// the named lists are kept next to the main ToDo file
+	ws := todo.NewWorkspace(todoFileName, listsDir(todoFileName, os.Getenv("TODO_DIR")), c.store, c.key)
+	filename, err := listFile(ws, c.listName)
 
-	// use the Get method to read ToDo items from file
-	if err := l.Get(todoFileName); err != nil {
+	switch {
+	case c.merge:
+		err = merge(c, os.Stdout)
+	case c.mergeDriver:
+		err = merge(c, os.Stderr)
+	case c.genKey != "":
+		err = runKeys(filename, c, os.Stdout)
+	case err != nil:
+	case c.encrypt || c.decrypt || c.rotateKey:
+		err = runKeys(filename, c, os.Stdout)
+	case c.lists || c.createList != "" || c.renameList != "" || c.deleteList != "" || c.toList != "" || c.all:
+		err = runLists(ws, c, os.Stdout)
+	case c.remind:
+		var n todo.Notifier
+		if n, err = newNotifier(c, os.Stdout); err == nil {
+			err = remind(filename, c, n, os.Stderr, nil)
+		}
+	case c.tui:
+		err = runTUI(filename, c)
+	case c.serve:
+		err = serve(filename, c, os.Stdout)
+	default:
+		err = run(filename, c, os.Stdin, os.Stdout)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
+
+// run executes the command selected by c against the ToDo file.
+// The file stays locked from reading it until the changes are
+// saved, so concurrent invocations don't lose each other's writes
+func run(filename string, c config, in io.Reader, out io.Writer) error {
+	store, err := newStore(filename, c)
+	if err != nil {
+		return err
+	}
+
+	// read the note before locking the file, writing it in an
+	// editor can take a while
+	note := ""
+	if c.annotate > 0 {
+		if note, err = getNote(in, c.editor, c.args...); err != nil {
+			return err
+		}
+	}
+
+	lock, err := todo.LockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+
+	// use the store to read ToDo items from file
+	if err := store.Load(l); err != nil {
+		return err
+	}
+
+	// every change is recorded in a journal next to the file
+	j, err := openJournal(filename+".journal", c)
+	if err != nil {
+		return err
+	}
+	before := append(todo.List{}, *l...)
+	op := ""
+
+	// the views show the archived items too when asked to
+	shown := l
+	if c.archived {
+		a, err := loadArchive(filename, c)
+		if err != nil {
+			return err
+		}
+		all := append(append(todo.List{}, *l...), *a...)
+		shown = &all
+	}
+
+	order, err := todo.ParseOrder(c.sortBy)
+	if err != nil {
+		return err
+	}
+	filter, err := viewFilter(shown, c, time.Now())
+	if err != nil {
+		return err
+	}
+	layout, err := todo.ParseLayout(layoutName(c))
+	if err != nil {
+		return err
+	}
 
 	// Decide what to do based on the number of arguments
 	// provided
 	switch {
-	// print verbose list
-	case *verbose:
-		switch {
-		case *u:
-			fmt.Println(l.UncompleteVerbose())
-		default:
-			fmt.Println(l.Verbose())
-		}
-	// For no extra arguments, print the list
-	case *list:
-		// List current ToDo items
-		switch {
-		case *u:
-			fmt.Println(l.Uncomplete())
-		default:
-			fmt.Println(l)
-		}
-	case *complete > 0:
-		// complete the given item
-		if err := l.Complete(*complete); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
-		// save the new list
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
-	case *add:
+	case c.report:
+		return report(shown, filter, c, out)
+	case c.trash:
+		trash := l.Trashed()
+		return trash.Render(out, layout, filter, order)
+	// print the selected tasks with the layout asked for
+	case c.list || c.verbose || c.json || c.format != "":
+		return shown.Render(out, layout, filter, order)
+	case c.tags:
+		fmt.Fprint(out, l.Tags())
+		return nil
+	case c.timeBy != "":
+		entries, err := shown.TimeReport(filter, c.timeBy, time.Now())
+		if err != nil {
+			return err
+		}
+		if c.csv {
+			return todo.WriteTimeCSV(out, c.timeBy, entries)
+		}
+		fmt.Fprint(out, todo.TimeString(entries))
+		return nil
+	case c.history:
+		fmt.Fprint(out, j.History())
+		return nil
+	case c.exp != "":
+		return exportTxt(l, c.exp, out)
+	case c.ics != "":
+		return exportICS(l, c.ics, out)
+	case c.icsDir != "":
+		return l.WriteICSDir(c.icsDir)
+	case c.imp != "":
+		if err := importTxt(l, c.imp, in); err != nil {
+			return err
+		}
+		op = "import"
+	case c.undo > 0:
+		if _, err := j.Undo(l, c.undo); err != nil {
+			return err
+		}
+	case c.redo > 0:
+		if _, err := j.Redo(l, c.redo); err != nil {
+			return err
+		}
+	case c.complete != "":
+		// complete the items selected
+		if err := bulk(l, c.complete, "Completed", "already completed", func(ids []int) (todo.BulkResult, error) {
+			return completeAll(l, ids, c.subtasks, in, out)
+		}, out); err != nil {
+			return err
+		}
+		op = "complete"
+	case c.add:
 		// when any arguments are provided, they will be used as the new task
-		t, err := getTask(os.Stdin, *m, flag.Args()...)
+		t, err := getTask(in, c.m, c.args...)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return err
 		}
 		l.Add(t)  // add task
+		op = "add"
 
-		// save the new list
-		if err := l.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		// set the optional fields on every added item
+		for k := len(*l) - strings.Count(t, "\n") - 1; k < len(*l); k++ {
+			if err := setFields(l, (*l)[k].ID, c); err != nil {
+				return err
+			}
+		}
+	case c.edit > 0:
+		if len(c.args) > 0 {
+			if err := l.Edit(c.edit, strings.Join(c.args, " ")); err != nil {
+				return err
+			}
 		}
-	case *delete > 0:
-		if err := l.Delete(*delete); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+		if err := setFields(l, c.edit, c); err != nil {
+			return err
 		}
+		op = "edit"
+	case c.reopen != "":
+		if err := bulk(l, c.reopen, "Reopened", "already pending", l.UncompleteAll, out); err != nil {
+			return err
+		}
+		op = "uncomplete"
+	case c.move > 0:
+		if err := l.Move(c.move, c.to); err != nil {
+			return err
+		}
+		op = "move"
+	case c.swap != "":
+		ids, err := parseIDs(c.swap)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 2 {
+			return fmt.Errorf("Invalid swap %q, expected two IDs", c.swap)
+		}
+		if err := l.Swap(ids[0], ids[1]); err != nil {
+			return err
+		}
+		op = "swap"
+	case c.annotate > 0:
+		if err := l.Annotate(c.annotate, note); err != nil {
+			return err
+		}
+		op = "annotate"
+	case c.delete != "":
+		if err := bulk(l, c.delete, "Deleted", "", l.TrashAll, out); err != nil {
+			return err
+		}
+		op = "delete"
+	case c.start > 0:
+		if err := l.Start(c.start); err != nil {
+			return err
+		}
+		op = "start"
+	case c.stop:
+		if err := l.Stop(); err != nil {
+			return err
+		}
+		op = "stop"
+	case c.snooze > 0:
+		if err := l.Snooze(c.snooze, time.Now().Add(c.snoozeFor)); err != nil {
+			return err
+		}
+		op = "snooze"
+	case c.restore > 0:
+		if err := l.Restore(c.restore); err != nil {
+			return err
+		}
+		op = "restore"
+	case c.empty:
+		fmt.Fprintf(out, "Deleted %s\n", itemCount(l.EmptyTrash()))
+		op = "empty-trash"
+	case c.archive > 0:
+		n, err := archive(filename, c, l)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Archived %s\n", itemCount(n))
+		op = todo.OpArchive
 	default:
 		// invalid flag provided
-		fmt.Fprintln(os.Stderr, "Invalid option")
-		os.Exit(1)
+		return errors.New("Invalid option")
+	}
+
+	if op != "" {
+		j.Record(op, before, *l)
 	}
+
+	// save the new list
+	if c.backup {
+		if err := todo.Backup(filename); err != nil {
+			return err
+		}
+	}
+	if err := store.Save(l); err != nil {
+		return err
+	}
+
+	// the journal is only written once the list is saved, so it
+	// never records changes missing from the file
+	return j.Save()
+}
+
+// report prints the statistics of the tasks accepted by filter
+// over the range of days given on the command line
+func report(l *todo.List, filter todo.Filter, c config, out io.Writer) error {
+	to := time.Now()
+	if c.until != "" {
+		d, err := time.ParseInLocation(todo.DateLayout, c.until, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid date %q: %w", c.until, err)
+		}
+		to = d
+	}
+	from := to.AddDate(0, 0, -29)
+	if c.since != "" {
+		d, err := time.ParseInLocation(todo.DateLayout, c.since, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid date %q: %w", c.since, err)
+		}
+		from = d
+	}
+
+	s, err := l.Stats(filter, from, to, c.by)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		js, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(js))
+		return nil
+	}
+	fmt.Fprint(out, s)
+	return nil
+}
+
+// loadArchive reads the archive of the ToDo file filename
+func loadArchive(filename string, c config) (*todo.List, error) {
+	store, err := newStore(todo.ArchiveFile(filename), c)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &todo.List{}
+	return a, store.Load(a)
+}
+
+// archive moves the items of l completed more than c.archive days
+// ago to the archive of filename. The archive is saved first, so the
+// items are never missing from both files
+func archive(filename string, c config, l *todo.List) (int, error) {
+	a, err := loadArchive(filename, c)
+	if err != nil {
+		return 0, err
+	}
+
+	n := l.Archive(a, time.Now().AddDate(0, 0, -c.archive))
+	if n == 0 {
+		return 0, nil
+	}
+
+	store, err := newStore(todo.ArchiveFile(filename), c)
+	if err != nil {
+		return 0, err
+	}
+	return n, store.Save(a)
+}
+
+// load reads the list in the ToDo file while holding its lock
+func load(filename string, c config) (*todo.List, error) {
+	store, err := newStore(filename, c)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := todo.LockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+	return l, store.Load(l)
+}
+
+// update applies change to the list in the ToDo file and saves it
+// like run does, recording the change in the journal as op. It's
+// used by the long-running modes, which only lock the file while
+// changing it
+func update(filename string, c config, op string, change func(l *todo.List) error) (*todo.List, error) {
+	store, err := newStore(filename, c)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := todo.LockFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	l := &todo.List{}
+	if err := store.Load(l); err != nil {
+		return nil, err
+	}
+	j, err := openJournal(filename+".journal", c)
+	if err != nil {
+		return nil, err
+	}
+	before := append(todo.List{}, *l...)
+
+	if err := change(l); err != nil {
+		return nil, err
+	}
+	j.Record(op, before, *l)
+
+	if c.backup {
+		if err := todo.Backup(filename); err != nil {
+			return nil, err
+		}
+	}
+	if err := store.Save(l); err != nil {
+		return nil, err
+	}
+
+	return l, j.Save()
 }
 
 // getTask function decides where to get the descirption for a new task: arguments or STDIN
@@ -120,3 +613,335 @@ func getTask(r io.Reader, m bool, args ...string) (string, error) {
 
 	return strings.Join(output, "\n"), nil
 }
+
+// getNote returns the text of a note: the arguments, the text
+// written in the editor, or all of r. Unlike getTask, multi-line
+// text is kept as it is
+func getNote(r io.Reader, editor bool, args ...string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+	if editor {
+		return editNote()
+	}
+
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// editNote opens $VISUAL or $EDITOR, vi if neither is set, on an
+// empty file and returns what was written in it
+func editNote() (string, error) {
+	f, err := os.CreateTemp("", "todo-note-*.txt")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// the editor may be given with arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), name)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Editor %s failed: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(name)
+	return string(data), err
+}
+
+// completeAll completes the items of ids. When some have open
+// subtasks they are completed too if subtasks is set or the user
+// agrees
+func completeAll(l *todo.List, ids []int, subtasks bool, in io.Reader, out io.Writer) (todo.BulkResult, error) {
+	r, err := l.CompleteAll(ids, subtasks)
+	if subtasks || !errors.Is(err, todo.ErrOpenSubtasks) {
+		return r, err
+	}
+
+	if !isTerminal(in) {
+		return r, fmt.Errorf("%w, or use -subtasks to complete them too", err)
+	}
+	fmt.Fprintf(out, "%s. Complete them too? [y/N] ", err)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return r, err
+	}
+
+	return l.CompleteAll(ids, true)
+}
+
+// bulk applies op to the items selected by sel, as IDs and ranges
+// of IDs or as a query, and prints a summary of the changes. Items
+// op leaves unchanged are reported as skipped
+func bulk(l *todo.List, sel, verb, skipped string, op func(ids []int) (todo.BulkResult, error), out io.Writer) error {
+	ids, err := l.Select(sel)
+	if err != nil {
+		return err
+	}
+	r, err := op(ids)
+	if err != nil {
+		return err
+	}
+
+	if len(r.Changed) > 0 || len(r.Skipped) == 0 {
+		fmt.Fprintf(out, "%s %s: %s\n", verb, itemCount(len(r.Changed)), joinIDs(r.Changed))
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(out, "Skipped %s %s: %s\n", itemCount(len(r.Skipped)), skipped, joinIDs(r.Skipped))
+	}
+	return nil
+}
+
+// itemCount prints a number of items, e.g. "1 item" or "3 items"
+func itemCount(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+// joinIDs prints a list of item IDs separated by commas
+func joinIDs(ids []int) string {
+	s := make([]string, len(ids))
+	for k, id := range ids {
+		s[k] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ", ")
+}
+
+// isTerminal reports whether r is an interactive terminal
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// setFields sets the fields of the item id given on the command line.
+// Empty values are left unchanged and "-" clears the field
+func setFields(l *todo.List, id int, c config) error {
+	priority, due, recur := c.priority, c.due, c.recur
+
+	switch priority {
+	case "":
+	case "-":
+		if err := l.SetPriority(id, ""); err != nil {
+			return err
+		}
+	default:
+		if err := l.SetPriority(id, priority); err != nil {
+			return err
+		}
+	}
+
+	switch due {
+	case "":
+	case "-":
+		if err := l.SetDue(id, time.Time{}); err != nil {
+			return err
+		}
+	default:
+		d, err := time.ParseInLocation(todo.DateLayout, due, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid due date %q: %w", due, err)
+		}
+		if err := l.SetDue(id, d); err != nil {
+			return err
+		}
+	}
+
+	switch recur {
+	case "":
+	case "-":
+		if err := l.SetRecurrence(id, nil); err != nil {
+			return err
+		}
+	default:
+		r, err := todo.ParseRecurrence(recur)
+		if err != nil {
+			return err
+		}
+		if err := l.SetRecurrence(id, r); err != nil {
+			return err
+		}
+	}
+
+	switch c.parent {
+	case "":
+	case "-":
+		if err := l.SetParent(id, 0); err != nil {
+			return err
+		}
+	default:
+		p, err := strconv.Atoi(c.parent)
+		if err != nil {
+			return fmt.Errorf("Invalid parent %q: %w", c.parent, err)
+		}
+		if err := l.SetParent(id, p); err != nil {
+			return err
+		}
+	}
+
+	blockers, err := parseIDs(c.block)
+	if err != nil {
+		return err
+	}
+	for _, b := range blockers {
+		if err := l.Block(id, b); err != nil {
+			return err
+		}
+	}
+
+	blockers, err = parseIDs(c.unblock)
+	if err != nil {
+		return err
+	}
+	for _, b := range blockers {
+		if err := l.Unblock(id, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseIDs parses a comma-separated list of item IDs
+func parseIDs(s string) ([]int, error) {
+	ids := []int{}
+	if s == "" {
+		return ids, nil
+	}
+
+	for _, v := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid item ID %q", v)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// layoutName returns the layout of the listed tasks asked for
+// on the command line
+func layoutName(c config) string {
+	switch {
+	case c.json:
+		return todo.LayoutJSON
+	case c.verbose:
+		return todo.LayoutVerbose
+	}
+	return c.format
+}
+
+// viewFilter builds the filter for the list views out of the
+// command-line flags. It returns nil when every task is shown
+func viewFilter(l *todo.List, c config, now time.Time) (todo.Filter, error) {
+	filters := []todo.Filter{}
+	if c.u {
+		filters = append(filters, todo.Pending)
+	}
+	if c.overdue {
+		filters = append(filters, todo.Overdue(now))
+	}
+	if c.week {
+		filters = append(filters, todo.DueThisWeek(now))
+	}
+	if c.next {
+		filters = append(filters, l.Unblocked())
+	}
+	if c.tag != "" {
+		f, err := todo.TagFilter(c.tag)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if c.query != "" {
+		f, err := todo.ParseQuery(c.query)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return todo.All(filters...), nil
+}
+
+// exportTxt writes the list as todo.txt to the file name,
+// or to out when name is "-"
+func exportTxt(l *todo.List, name string, out io.Writer) error {
+	// the items in the trash aren't exported
+	cp := append(todo.List{}, *l...)
+	l = &cp
+	l.EmptyTrash()
+
+	if name == "-" {
+		return l.WriteTxt(out)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := l.WriteTxt(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// exportICS writes the list as iCalendar to the file name,
+// or to out when name is "-"
+func exportICS(l *todo.List, name string, out io.Writer) error {
+	if name == "-" {
+		return l.WriteICS(out)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := l.WriteICS(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// importTxt adds the tasks of the todo.txt file name to the
+// list, reading them from in when name is "-"
+func importTxt(l *todo.List, name string, in io.Reader) error {
+	if name == "-" {
+		return l.ReadTxt(in)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.ReadTxt(f)
+}
@@ -7,7 +7,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -39,6 +41,9 @@ func TestMain(m *testing.M) {
 	fmt.Println("Cleaning up...")
 	os.Remove(binName)
 	os.Remove(fileName)
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".journal")
+	os.Remove(".todo.archive.json")
 
 	os.Exit(result)
 }
@@ -76,6 +81,36 @@ func TestTodoCLI(t *testing.T) {
 		}
 	})
 
+	t.Run("AddConcurrently", func(t *testing.T) {
+		// use a file of their own so later tests see only the first tasks
+		env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), "todo.json"))
+
+		cmds := []*exec.Cmd{}
+		for i := 0; i < 10; i++ {
+			cmd := exec.Command(cmdPath, "-add", fmt.Sprintf("concurrent task %d", i))
+			cmd.Env = env
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			cmds = append(cmds, cmd)
+		}
+		for _, cmd := range cmds {
+			if err := cmd.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-list")
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(out), "concurrent task"); n != 10 {
+			t.Fatalf("Expected %d concurrent tasks, got %d instead", 10, n)
+		}
+	})
+
 	t.Run("ListTasks", func(t *testing.T) {
 		cmd := exec.Command(cmdPath, "-list")
 		out, err := cmd.CombinedOutput()
@@ -106,6 +141,472 @@ func TestTodoCLI(t *testing.T) {
 		if expected != string(out) {
 			t.Errorf("Expected %q, got %q instead", expected, string(out))
 		}
+
+		// a single ID is checked like a range, completed items are skipped
+		out, err = exec.Command(cmdPath, "-complete", "1").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected = "Skipped 1 item already completed: 1\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+		if _, err := exec.Command(cmdPath, "-complete", "999").CombinedOutput(); err == nil {
+			t.Error("Expected an error completing a missing item")
+		}
+	})
+
+	t.Run("EditPriorityAndDue", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-edit", "2", "-p", "a", "-due", "2026-01-02")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		cmd = exec.Command(cmdPath, "-list", "-sort", "priority")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		expected := fmt.Sprintf("  2: (A) %s (due 2026-01-02)\nX 1: %s\n", task2, task1)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// clear the fields again so later tests see the plain task
+		cmd = exec.Command(cmdPath, "-edit", "2", "-p", "-", "-due", "-")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("AnnotateTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-annotate", "2")
+		cmd.Stdin = strings.NewReader("first line\nsecond line\n")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		out, err := exec.Command(cmdPath, "-verbose").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if !strings.Contains(string(out), ": first line\n\t    second line\n") {
+			t.Errorf("Expected the note in the verbose output, got %q", string(out))
+		}
+
+		if err := exec.Command(cmdPath, "-undo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("EditMoveUncomplete", func(t *testing.T) {
+		steps := [][]string{
+			{"-edit", "2", "fixed", "typo"},
+			{"-move", "2", "-to", "1"},
+			{"-uncomplete", "1"},
+		}
+		for _, args := range steps {
+			if out, err := exec.Command(cmdPath, args...).CombinedOutput(); err != nil {
+				t.Fatalf("running command %v: %v %s", args, err, out)
+			}
+		}
+
+		out, err := exec.Command(cmdPath, "-list").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected := fmt.Sprintf("  2: fixed typo\n  1: %s\n", task1)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// put the list back for the next tests
+		if err := exec.Command(cmdPath, "-undo", "3").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("TrackTime", func(t *testing.T) {
+		for _, args := range [][]string{{"-start", "2"}, {"-stop"}} {
+			if out, err := exec.Command(cmdPath, args...).CombinedOutput(); err != nil {
+				t.Fatalf("running command %v: %v %s", args, err, out)
+			}
+		}
+		if err := exec.Command(cmdPath, "-stop").Run(); err == nil {
+			t.Errorf("Expected error stopping with no started item")
+		}
+
+		out, err := exec.Command(cmdPath, "-time-report", "day", "-csv").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected := "day,hours\n" + time.Now().Format("2006-01-02") + ",0.00\n"
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		if err := exec.Command(cmdPath, "-undo", "2").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		today := time.Now().Format("2006-01-02")
+		out, err := exec.Command(cmdPath, "-report", "-since", today, "-json").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		for _, e := range []string{`"Created": 2,`, `"Completed": 1,`, `"Open": 1`} {
+			if !strings.Contains(string(out), e) {
+				t.Errorf("Expected report to contain %q, got %q", e, string(out))
+			}
+		}
+	})
+
+	t.Run("ListFormats", func(t *testing.T) {
+		testCases := map[string]string{
+			"markdown":          fmt.Sprintf("- [x] %s\n- [ ] %s\n", task1, task2),
+			"{{.ID}}:{{.Done}}": "1:true\n2:false\n",
+		}
+		for format, expected := range testCases {
+			out, err := exec.Command(cmdPath, "-format", format).CombinedOutput()
+			if err != nil {
+				t.Fatalf("running command: %v", err)
+			}
+			if expected != string(out) {
+				t.Errorf("Expected %q, got %q instead", expected, string(out))
+			}
+		}
+	})
+
+	t.Run("UndoRedo", func(t *testing.T) {
+		if err := exec.Command(cmdPath, "-delete", "2").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if err := exec.Command(cmdPath, "-undo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		out, err := exec.Command(cmdPath, "-list").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected := fmt.Sprintf("X 1: %s\n  2: %s\n", task1, task2)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		if err := exec.Command(cmdPath, "-redo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if err := exec.Command(cmdPath, "-undo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if err := exec.Command(cmdPath, "-redo", "2").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		out, err = exec.Command(cmdPath, "-list").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected = fmt.Sprintf("X 1: %s\n", task1)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// put task2 back for the next tests
+		if err := exec.Command(cmdPath, "-undo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("ExportImportTxt", func(t *testing.T) {
+		out, err := exec.Command(cmdPath, "-export", "-").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if n := strings.Count(string(out), "\n"); n != 2 || !strings.HasPrefix(string(out), "x ") {
+			t.Fatalf("Unexpected todo.txt output %q", string(out))
+		}
+
+		// importing the export again duplicates the tasks with new IDs
+		cmd := exec.Command(cmdPath, "-import", "-")
+		cmd.Stdin = strings.NewReader(string(out))
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		list, err := exec.Command(cmdPath, "-list").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected := fmt.Sprintf("X 1: %s\n  2: %s\nX 3: %s\n  4: %s\n", task1, task2, task1, task2)
+		if expected != string(list) {
+			t.Errorf("Expected %q, got %q instead", expected, string(list))
+		}
+
+		if err := exec.Command(cmdPath, "-undo", "1").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+	})
+
+	t.Run("TrashRestore", func(t *testing.T) {
+		if err := exec.Command(cmdPath, "-delete", "2").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+
+		out, err := exec.Command(cmdPath, "-trash").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected := fmt.Sprintf("  2: %s\n", task2)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		if err := exec.Command(cmdPath, "-restore", "2").Run(); err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		out, err = exec.Command(cmdPath, "-list").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		expected = fmt.Sprintf("X 1: %s\n  2: %s\n", task1, task2)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+	})
+
+	t.Run("ArchiveTask", func(t *testing.T) {
+		// nothing was completed more than a day ago
+		out, err := exec.Command(cmdPath, "-archive", "1").CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if string(out) != "Archived 0 items\n" {
+			t.Errorf("Expected no archived items, got %q", string(out))
+		}
+	})
+
+	t.Run("NamedLists", func(t *testing.T) {
+		env := append(os.Environ(), "TODO_DIR="+t.TempDir())
+		run := func(args ...string) string {
+			cmd := exec.Command(cmdPath, args...)
+			cmd.Env = env
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("running command %v: %v: %s", args, err, out)
+			}
+			return string(out)
+		}
+
+		run("-create-list", "work")
+		run("-create-list", "home")
+		run("-L", "work", "-add", "call bob")
+		if out := run("-L", "work", "-move", "1", "-to-list", "home"); out != "Moved item 1 to list home as item 1\n" {
+			t.Errorf("Unexpected move output %q", out)
+		}
+
+		expected := "default: 1 pending, 2 total\nhome: 1 pending, 1 total\nwork: 0 pending, 0 total\n"
+		if out := run("-lists"); out != expected {
+			t.Errorf("Expected %q, got %q instead", expected, out)
+		}
+
+		expected = fmt.Sprintf("default:\nX 1: %s\n  2: %s\n\nhome:\n  1: call bob\n\nwork:\n", task1, task2)
+		if out := run("-all"); out != expected {
+			t.Errorf("Expected %q, got %q instead", expected, out)
+		}
+
+		run("-rename-list", "home,house")
+		run("-delete-list", "work")
+		if out := run("-L", "house", "-list"); out != "  1: call bob\n" {
+			t.Errorf("Expected the renamed list to keep its items, got %q", out)
+		}
+
+		cmd := exec.Command(cmdPath, "-L", "work", "-list")
+		cmd.Env = env
+		if err := cmd.Run(); err == nil {
+			t.Error("Expected an error using a deleted list")
+		}
+	})
+
+	t.Run("MergeFiles", func(t *testing.T) {
+		dir := t.TempDir()
+		write := func(name, content string) string {
+			file := filepath.Join(dir, name)
+			if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return file
+		}
+		base := write("base.json", `[{"ID":1,"Task":"a"},{"ID":2,"Task":"b"}]`)
+		ours := write("ours.json", `[{"ID":1,"Task":"a ours"},{"ID":2,"Task":"b"}]`)
+		theirs := write("theirs.json", `[{"ID":1,"Task":"a","Done":true},{"ID":2,"Task":"b theirs"}]`)
+
+		out, err := exec.Command(cmdPath, "-merge", base, ours, theirs).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		cmd := exec.Command(cmdPath, "-list")
+		cmd.Env = append(os.Environ(), "TODO_FILENAME="+ours)
+		out, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v", err)
+		}
+		if expected := "X 1: a ours\n  2: b theirs\n"; string(out) != expected {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// both sides edit item 2 now
+		write("base.json", `[{"ID":2,"Task":"b"}]`)
+		write("ours.json", `[{"ID":2,"Task":"b ours"}]`)
+		out, err = exec.Command(cmdPath, "-merge-driver", base, ours, theirs).Output()
+		if err == nil {
+			t.Error("Expected the merge to fail with conflicts")
+		}
+		if len(out) != 0 {
+			t.Errorf("Expected the driver to report on STDERR, got %q", string(out))
+		}
+		out, _ = exec.Command(cmdPath, "-merge", "-json", base, ours, theirs).Output()
+		if !strings.Contains(string(out), `"Kind": "edit/edit"`) {
+			t.Errorf("Expected an edit/edit conflict, got %q", string(out))
+		}
+	})
+
+	t.Run("EncryptFile", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "todo.json")
+		run := func(env []string, args ...string) (string, error) {
+			cmd := exec.Command(cmdPath, args...)
+			cmd.Env = append(append(os.Environ(), "TODO_FILENAME="+file), env...)
+			out, err := cmd.CombinedOutput()
+			return string(out), err
+		}
+		old := []string{"TODO_PASSPHRASE=old secret"}
+		rotate := []string{"TODO_PASSPHRASE=old secret", "TODO_NEW_PASSPHRASE=new secret"}
+		current := []string{"TODO_PASSPHRASE=new secret"}
+
+		if out, err := run(nil, "-add", "call ACME"); err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		if _, err := run(old, "-list"); err == nil {
+			t.Error("Expected an error listing a plain file with a passphrase")
+		}
+		steps := []struct {
+			env  []string
+			args []string
+		}{
+			{old, []string{"-encrypt"}},
+			{old, []string{"-add", "pay ACME"}},
+			{rotate, []string{"-rotate-key"}},
+		}
+		for _, s := range steps {
+			if out, err := run(s.env, s.args...); err != nil {
+				t.Fatalf("running %v: %v: %s", s.args, err, out)
+			}
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "ACME") {
+			t.Errorf("Expected the file to be encrypted, got %q", string(data))
+		}
+		if _, err := run(old, "-list"); err == nil {
+			t.Error("Expected an error listing with the old passphrase")
+		}
+
+		if out, err := run(current, "-decrypt"); err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		out, err := run(nil, "-list")
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		if expected := "  1: call ACME\n  2: pay ACME\n"; out != expected {
+			t.Errorf("Expected %q, got %q instead", expected, out)
+		}
+		if out, err := run(nil, "-undo", "1"); err != nil {
+			t.Errorf("Expected the decrypted journal to be readable: %v: %s", err, out)
+		}
+	})
+
+	t.Run("BulkOperations", func(t *testing.T) {
+		for _, task := range []string{"bulk task A", "bulk task B", "bulk task C"} {
+			if err := exec.Command(cmdPath, "-add", task).Run(); err != nil {
+				t.Fatalf("running command: %v", err)
+			}
+		}
+
+		// select the new tasks with a query, and read their IDs
+		out, err := exec.Command(cmdPath, "-complete", `text~"bulk task"`).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		var a, b, c int
+		if _, err := fmt.Sscanf(string(out), "Completed 3 items: %d, %d, %d\n", &a, &b, &c); err != nil {
+			t.Fatalf("Expected a summary of 3 completed items, got %q", string(out))
+		}
+
+		out, err = exec.Command(cmdPath, "-uncomplete", fmt.Sprintf("%d-%d", a, b)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected := fmt.Sprintf("Reopened 2 items: %d, %d\n", a, b)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		out, err = exec.Command(cmdPath, "-complete", fmt.Sprintf("%d-%d", a, c)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected = fmt.Sprintf("Completed 2 items: %d, %d\nSkipped 1 item already completed: %d\n", a, b, c)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// a range skips the items in the trash
+		out, err = exec.Command(cmdPath, "-delete", fmt.Sprint(b)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected = fmt.Sprintf("Deleted 1 item: %d\n", b)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+		out, err = exec.Command(cmdPath, "-uncomplete", fmt.Sprintf("%d-%d", a, c)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected = fmt.Sprintf("Reopened 2 items: %d, %d\n", a, c)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		// nothing changes when an item doesn't exist
+		out, err = exec.Command(cmdPath, "-delete", fmt.Sprintf("%d,%d", a, c+1)).CombinedOutput()
+		if err == nil {
+			t.Fatal("Expected error deleting a missing item")
+		}
+		expected = fmt.Sprintf("Item %d doesn't exist\n", c+1)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+
+		out, err = exec.Command(cmdPath, "-delete", fmt.Sprintf("%d,%d-%d", a, b, c)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("running command: %v: %s", err, out)
+		}
+		expected = fmt.Sprintf("Deleted 2 items: %d, %d\n", a, c)
+		if expected != string(out) {
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
+		}
+		if err := exec.Command(cmdPath, "-empty-trash").Run(); err != nil {
+			t.Fatal(err)
+		}
 	})
 
 	t.Run("DeleteTask", func(t *testing.T) {
@@ -121,9 +622,9 @@ func TestTodoCLI(t *testing.T) {
 			t.Fatal(fmt.Sprintf("getting output: %v", err))
 		}
 
-		expected := fmt.Sprintf(" 1: %s\n", task2)  // since task1 is deleted
+		expected := fmt.Sprintf("  2: %s\n", task2)  // task2 keeps its ID after task1 is deleted
 		if expected != string(out) {
-			t.Errorf("Expected %q, got %q instead", task2, string(out))
+			t.Errorf("Expected %q, got %q instead", expected, string(out))
 		}
 	})
 }
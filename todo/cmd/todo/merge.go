@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// merge merges the changes made to the BASE file in the OURS and
+// THEIRS files given as arguments, and writes the result to OURS.
+// The conflicts are reported to out, as JSON with -json, and make
+// the merge fail once the result is written, so git leaves the file
+// to be checked when merge runs as its merge driver:
+//
+//	git config merge.todo.driver "todo -merge-driver %O %A %B"
+//	echo ".todo.json merge=todo" >> .gitattributes
+func merge(c config, out io.Writer) error {
+	if len(c.args) != 3 {
+		return errors.New("Expected the BASE, OURS and THEIRS files to merge")
+	}
+
+	// git gives the driver temporary files, which aren't locked
+	ours := c.args[1]
+	if !c.mergeDriver {
+		lock, err := todo.LockFile(ours)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	lists := make([]todo.List, len(c.args))
+	for k, file := range c.args {
+		store, err := newStore(file, c)
+		if err != nil {
+			return err
+		}
+		if err := store.Load(&lists[k]); err != nil {
+			return fmt.Errorf("Can't read %s: %w", file, err)
+		}
+	}
+
+	merged, conflicts := todo.Merge(lists[0], lists[1], lists[2])
+
+	store, err := newStore(ours, c)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(&merged); err != nil {
+		return err
+	}
+
+	if c.json {
+		js, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(js))
+	} else {
+		for _, conflict := range conflicts {
+			fmt.Fprintln(out, conflict)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("Merged %s with %d conflicts", ours, len(conflicts))
+	}
+	return nil
+}
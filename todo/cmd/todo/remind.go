@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// pollInterval is how often the remind mode checks the ToDo file
+// for changes
+var pollInterval = 2 * time.Second
+
+// remind watches the ToDo file and notifies the tasks coming due
+// until stop is closed. The list is read again whenever the file
+// changes, so the reminders follow the edits made meanwhile.
+// Errors reading the file or notifying are reported to errOut
+// without stopping
+func remind(filename string, c config, n todo.Notifier, errOut io.Writer, stop <-chan struct{}) error {
+	at, err := parseTimeOfDay(c.remindAt)
+	if err != nil {
+		return err
+	}
+	s := todo.NewScheduler(todo.SystemClock, at)
+
+	l := &todo.List{}
+	var modTime time.Time
+	for {
+		if info, err := os.Stat(filename); err == nil && !info.ModTime().Equal(modTime) {
+			modTime = info.ModTime()
+			if nl, err := load(filename, c); err != nil {
+				fmt.Fprintln(errOut, err)
+			} else {
+				l = nl
+			}
+		}
+
+		for _, r := range s.Due(l) {
+			if err := n.Notify(r); err != nil {
+				fmt.Fprintln(errOut, err)
+			}
+		}
+
+		// wake up for the next reminder, or to check the file
+		wait := pollInterval
+		if next := s.Next(l); !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// parseTimeOfDay parses a HH:MM time into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// newNotifier returns the notifier selected with -notify
+func newNotifier(c config, out io.Writer) (todo.Notifier, error) {
+	switch c.notify {
+	case "stdout":
+		return writerNotifier{w: out}, nil
+	case "command":
+		if c.notifyCmd == "" {
+			return nil, errors.New("Missing command, use -notify-cmd with -notify command")
+		}
+		return commandNotifier{command: c.notifyCmd}, nil
+	case "desktop":
+		return newDesktopNotifier()
+	}
+	return nil, fmt.Errorf("Invalid notifier %q, expected stdout, command or desktop", c.notify)
+}
+
+// writerNotifier prints the reminders, one per line
+type writerNotifier struct {
+	w io.Writer
+}
+
+func (n writerNotifier) Notify(r todo.Reminder) error {
+	_, err := fmt.Fprintf(n.w, "%s %s\n", time.Now().Format(todo.SnoozeLayout), r)
+	return err
+}
+
+// commandNotifier runs a shell command for every reminder, with
+// the task given in the TODO_ID, TODO_TASK, TODO_DUE and
+// TODO_MESSAGE environment variables
+type commandNotifier struct {
+	command string
+}
+
+func (n commandNotifier) Notify(r todo.Reminder) error {
+	cmd := exec.Command("sh", "-c", n.command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", n.command)
+	}
+	cmd.Env = append(os.Environ(),
+		"TODO_ID="+strconv.Itoa(r.ID),
+		"TODO_TASK="+r.Task,
+		"TODO_DUE="+r.Due.Format(todo.DateLayout),
+		"TODO_MESSAGE="+r.String(),
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Notification command failed: %w: %s", err, out)
+	}
+	return nil
+}
+
+// desktopNotifier shows the reminders as desktop notifications,
+// with notify-send on Linux and the BSDs, osascript on macOS
+type desktopNotifier struct {
+	args func(title, message string) []string
+}
+
+func newDesktopNotifier() (desktopNotifier, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return desktopNotifier{args: func(title, message string) []string {
+			return []string{"osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title)}
+		}}, nil
+	case "windows":
+		return desktopNotifier{}, errors.New("Desktop notifications aren't supported on Windows, use -notify command")
+	}
+	return desktopNotifier{args: func(title, message string) []string {
+		return []string{"notify-send", title, message}
+	}}, nil
+}
+
+func (n desktopNotifier) Notify(r todo.Reminder) error {
+	args := n.args(fmt.Sprintf("Task %d is due %s", r.ID, r.Due.Format(todo.DateLayout)), r.Task)
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", args[0], err, out)
+	}
+	return nil
+}
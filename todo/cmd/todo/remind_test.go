@@ -0,0 +1,89 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chanNotifier sends the reminders to a channel
+type chanNotifier chan todo.Reminder
+
+func (n chanNotifier) Notify(r todo.Reminder) error {
+	n <- r
+	return nil
+}
+
+// TestRemind tests that the remind mode notifies the tasks due,
+// including the ones added to the file while it runs
+func TestRemind(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = 10 * time.Millisecond
+
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	c := config{remindAt: "00:00"}
+	addDue := func(task string) {
+		_, err := update(filename, c, "add", func(l *todo.List) error {
+			l.Add(task)
+			return l.SetDue((*l)[len(*l)-1].ID, time.Now().AddDate(0, 0, -1))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addDue("first")
+
+	n := make(chanNotifier)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- remind(filename, c, n, io.Discard, stop) }()
+
+	for _, task := range []string{"first", "second"} {
+		select {
+		case r := <-n:
+			if r.Task != task {
+				t.Errorf("Expected a reminder for %q, got %q instead", task, r.Task)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("No reminder for %q", task)
+		}
+		if task == "first" {
+			// make the file look changed even on coarse timestamps
+			addDue("second")
+			os.Chtimes(filename, time.Now(), time.Now().Add(time.Second))
+		}
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	n := commandNotifier{command: `echo "$TODO_ID $TODO_TASK" > ` + out}
+
+	if err := n.Notify(todo.Reminder{ID: 3, Task: "pay rent"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "3 pay rent" {
+		t.Errorf("Expected %q, got %q instead", "3 pay rent", got)
+	}
+
+	if err := (commandNotifier{command: "exit 1"}).Notify(todo.Reminder{}); err == nil {
+		t.Error("Expected an error from a failing command")
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBody is the largest request body the server accepts
+const maxBody = 1 << 20
+
+// server exposes a ToDo file over a JSON API:
+//
+//	GET    /tasks               list the tasks, filtered by the query
+//	                            parameters pending, overdue, week, next,
+//	                            tag, q and sort like the command line
+//	POST   /tasks               add tasks
+//	GET    /tasks/ID            show a task
+//	PATCH  /tasks/ID            edit a task
+//	DELETE /tasks/ID            delete a task
+//	POST   /tasks/ID/complete   complete a task, ?subtasks=true completes
+//	                            its open subtasks too
+//	POST   /tasks/ID/notes      add a note to a task
+//
+// Every request locks the file like the command line does, so both
+// can be used at the same time. Requests must name the server by
+// localhost or by its address, come from its own origin and send
+// their bodies as JSON, so web pages can't reach the API through
+// the browser
+type server struct {
+	filename string
+	format   string
+	token    string
+	addr     string
+	key      *todo.Key
+}
+
+// taskRequest is the body of the requests adding or editing tasks.
+// Like on the command line, empty fields are left unchanged and "-"
+// clears a field
+type taskRequest struct {
+	Task     string `json:"task"`
+	Priority string `json:"priority"`
+	Due      string `json:"due"`
+	Recur    string `json:"recur"`
+	Parent   string `json:"parent"`
+	Block    []int  `json:"block"`
+	Unblock  []int  `json:"unblock"`
+}
+
+// errNotFound is returned for requests about missing items
+var errNotFound = errors.New("Not found")
+
+// newServer returns the API handler for the ToDo file, encrypted
+// with key when it isn't nil, served on addr. When token isn't empty,
+// requests need it as a bearer token
+func newServer(filename, format, token, addr string, key *todo.Key) http.Handler {
+	s := &server{filename: filename, format: format, token: token, addr: addr, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", s.tasks)
+	mux.HandleFunc("/tasks/", s.task)
+
+	return s.guard(s.auth(mux))
+}
+
+// serve runs the API server on addr until it fails
+func serve(filename string, c config, out io.Writer) error {
+	srv := &http.Server{
+		Addr:              c.addr,
+		Handler:           newServer(filename, c.store, c.token, c.addr, c.key),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(out, "Serving %s on http://%s\n", filename, c.addr)
+	return srv.ListenAndServe()
+}
+
+// guard rejects the requests naming another host, the requests from
+// other origins and the request bodies that aren't JSON
+func (s *server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("Invalid host %q", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("Cross-origin request from %q", origin))
+				return
+			}
+		}
+		if r.ContentLength != 0 && r.Method != http.MethodGet {
+			if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("Request body must be application/json"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, from the Host header, names the
+// server: localhost or the host it listens on
+func (s *server) allowedHost(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	name = strings.ToLower(strings.Trim(name, "[]"))
+
+	switch name {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	case "":
+		return false
+	}
+	bind, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		bind = s.addr
+	}
+	return strings.EqualFold(name, strings.Trim(bind, "[]"))
+}
+
+// auth rejects the requests without the bearer token
+func (s *server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("Invalid or missing token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tasks handles /tasks
+func (s *server) tasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.list(w, r)
+	case http.MethodPost:
+		s.add(w, r)
+	default:
+		methodNotAllowed(w, "GET, POST")
+	}
+}
+
+// task handles /tasks/ID and the actions below it
+func (s *server) task(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id < 1 || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		s.get(w, id)
+	case action == "" && r.Method == http.MethodPatch:
+		s.edit(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		s.update(w, http.StatusOK, "delete", 0, func(l *todo.List) ([]int, error) {
+			return nil, checked(l, id, l.Trash)
+		})
+	case action == "":
+		methodNotAllowed(w, "GET, PATCH, DELETE")
+	case action == "complete" && r.Method == http.MethodPost:
+		subtasks := r.URL.Query().Get("subtasks") == "true"
+		s.update(w, http.StatusOK, "complete", id, func(l *todo.List) ([]int, error) {
+			if subtasks {
+				return nil, checked(l, id, l.CompleteWithSubtasks)
+			}
+			return nil, checked(l, id, l.Complete)
+		})
+	case action == "notes" && r.Method == http.MethodPost:
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := decode(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.update(w, http.StatusCreated, "annotate", id, func(l *todo.List) ([]int, error) {
+			return nil, checked(l, id, func(id int) error { return l.Annotate(id, req.Text) })
+		})
+	case action == "complete" || action == "notes":
+		methodNotAllowed(w, "POST")
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// list writes the tasks selected by the query parameters
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	c := config{
+		u:       q.Get("pending") == "true",
+		overdue: q.Get("overdue") == "true",
+		week:    q.Get("week") == "true",
+		next:    q.Get("next") == "true",
+		tag:     q.Get("tag"),
+		query:   q.Get("q"),
+		sortBy:  q.Get("sort"),
+	}
+
+	order, err := todo.ParseOrder(c.sortBy)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	l, err := load(s.filename, config{store: s.format, key: s.key})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	filter, err := viewFilter(l, c, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	layout, _ := todo.ParseLayout(todo.LayoutJSON)
+	w.Header().Set("Content-Type", "application/json")
+	l.Render(w, layout, filter, order)
+}
+
+// get writes the task id
+func (s *server) get(w http.ResponseWriter, id int) {
+	l, err := load(s.filename, config{store: s.format, key: s.key})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeItems(w, http.StatusOK, l, []int{id}, true)
+}
+
+// add adds the tasks of the request, one per line of its text,
+// and writes them
+func (s *server) add(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	task := strings.TrimSpace(req.Task)
+	if task == "" {
+		writeError(w, http.StatusBadRequest, errors.New("Missing task"))
+		return
+	}
+
+	s.update(w, http.StatusCreated, "add", 0, func(l *todo.List) ([]int, error) {
+		l.Add(task)
+
+		ids := []int{}
+		for k := len(*l) - strings.Count(task, "\n") - 1; k < len(*l); k++ {
+			id := (*l)[k].ID
+			if err := setFields(l, id, req.config()); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	})
+}
+
+// edit changes the fields of the task id given in the request
+func (s *server) edit(w http.ResponseWriter, r *http.Request, id int) {
+	var req taskRequest
+	if err := decode(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.update(w, http.StatusOK, "edit", id, func(l *todo.List) ([]int, error) {
+		if !exists(l, id) {
+			return nil, errNotFound
+		}
+		if req.Task != "" {
+			if err := l.Edit(id, req.Task); err != nil {
+				return nil, err
+			}
+		}
+		return nil, setFields(l, id, req.config())
+	})
+}
+
+// update applies change to the ToDo file, recording it in the journal
+// as op, and writes the items whose IDs change returns, or the item
+// id when it isn't 0
+func (s *server) update(w http.ResponseWriter, status int, op string, id int, change func(l *todo.List) ([]int, error)) {
+	var ids []int
+	var changeErr error
+	l, err := update(s.filename, config{store: s.format, key: s.key}, op, func(l *todo.List) error {
+		ids, changeErr = change(l)
+		return changeErr
+	})
+	switch {
+	case errors.Is(changeErr, errNotFound):
+		writeError(w, http.StatusNotFound, changeErr)
+		return
+	case changeErr != nil:
+		writeError(w, http.StatusBadRequest, changeErr)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if id != 0 {
+		ids = []int{id}
+	}
+	if len(ids) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeItems(w, status, l, ids, op != "add")
+}
+
+// config returns the command-line options matching the request,
+// to be used with setFields
+func (req taskRequest) config() config {
+	c := config{
+		priority: req.Priority,
+		due:      req.Due,
+		recur:    req.Recur,
+		parent:   req.Parent,
+	}
+
+	ids := []string{}
+	for _, id := range req.Block {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	c.block = strings.Join(ids, ",")
+
+	ids = []string{}
+	for _, id := range req.Unblock {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	c.unblock = strings.Join(ids, ",")
+
+	return c
+}
+
+// checked calls fn for id, returning errNotFound if the item
+// doesn't exist
+func checked(l *todo.List, id int, fn func(id int) error) error {
+	if !exists(l, id) {
+		return errNotFound
+	}
+	return fn(id)
+}
+
+// exists reports whether the item id is in the list and not in
+// the trash
+func exists(l *todo.List, id int) bool {
+	for _, t := range *l {
+		if t.ID == id && !t.Removed && t.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// decode reads the JSON body of the request into v
+func decode(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("Invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeItems writes the items with the given IDs, a single item is
+// written as an object when single is set
+func writeItems(w http.ResponseWriter, status int, l *todo.List, ids []int, single bool) {
+	items := todo.List{}
+	for _, id := range ids {
+		for _, t := range *l {
+			if t.ID == id && !t.Removed && t.DeletedAt == nil {
+				items = append(items, t)
+			}
+		}
+	}
+	if len(items) == 0 {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	var v interface{} = items
+	if single && len(items) == 1 {
+		v = items[0]
+	}
+	writeJSON(w, status, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type apiItem struct {
+	ID       int
+	Task     string
+	Done     bool
+	Priority string
+}
+
+// do sends a request to the handler and decodes its JSON response
+func do(t *testing.T, h http.Handler, method, target, body string, v interface{}) int {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Host = "localhost:8080"
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("Invalid response %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestServer(t *testing.T) {
+	h := newServer(filepath.Join(t.TempDir(), "todo.json"), "", "", "localhost:8080", nil)
+
+	var added []apiItem
+	if code := do(t, h, "POST", "/tasks", `{"task": "task 1\ntask 2", "priority": "b"}`, &added); code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d instead", http.StatusCreated, code)
+	}
+	if len(added) != 2 || added[1].ID != 2 || added[1].Priority != "B" {
+		t.Fatalf("Unexpected added items %+v", added)
+	}
+
+	var item apiItem
+	if code := do(t, h, "POST", "/tasks/1/complete", "", &item); code != http.StatusOK || !item.Done {
+		t.Errorf("Expected item 1 to be completed, got %d %+v", code, item)
+	}
+
+	// the cleared priority is omitted from the response
+	item = apiItem{}
+	if code := do(t, h, "PATCH", "/tasks/2", `{"task": "task two", "priority": "-"}`, &item); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d instead", http.StatusOK, code)
+	}
+	if item.Task != "task two" || item.Priority != "" {
+		t.Errorf("Unexpected edited item %+v", item)
+	}
+
+	var list []apiItem
+	do(t, h, "GET", "/tasks?pending=true", "", &list)
+	if len(list) != 1 || list[0].ID != 2 {
+		t.Errorf("Expected only item 2 to be pending, got %+v", list)
+	}
+	do(t, h, "GET", "/tasks?q="+"text~%22two%22", "", &list)
+	if len(list) != 1 || list[0].ID != 2 {
+		t.Errorf("Expected only item 2 to match the query, got %+v", list)
+	}
+
+	if code := do(t, h, "DELETE", "/tasks/1", "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d instead", http.StatusNoContent, code)
+	}
+	do(t, h, "GET", "/tasks", "", &list)
+	if len(list) != 1 {
+		t.Errorf("Expected %d items, got %d instead", 1, len(list))
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	h := newServer(filepath.Join(t.TempDir(), "todo.json"), "", "", "localhost:8080", nil)
+	do(t, h, "POST", "/tasks", `{"task": "task 1"}`, nil)
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{"MissingItem", "GET", "/tasks/5", "", http.StatusNotFound},
+		{"CompleteMissingItem", "POST", "/tasks/5/complete", "", http.StatusNotFound},
+		{"InvalidID", "GET", "/tasks/abc", "", http.StatusNotFound},
+		{"InvalidBody", "POST", "/tasks", `{"text": "task"}`, http.StatusBadRequest},
+		{"EmptyTask", "POST", "/tasks", `{"task": " "}`, http.StatusBadRequest},
+		{"InvalidPriority", "PATCH", "/tasks/1", `{"priority": "AB"}`, http.StatusBadRequest},
+		{"InvalidQuery", "GET", "/tasks?q=done%3Amaybe", "", http.StatusBadRequest},
+		{"WrongMethod", "PUT", "/tasks", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res map[string]string
+			if code := do(t, h, tc.method, tc.target, tc.body, &res); code != tc.code {
+				t.Errorf("Expected status %d, got %d instead", tc.code, code)
+			}
+			if res["error"] == "" {
+				t.Errorf("Expected an error message")
+			}
+		})
+	}
+}
+
+func TestServerToken(t *testing.T) {
+	h := newServer(filepath.Join(t.TempDir(), "todo.json"), "", "secret", "localhost:8080", nil)
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/tasks", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d instead", http.StatusUnauthorized, w.Code)
+	}
+
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d with token, got %d instead", http.StatusOK, w.Code)
+	}
+}
+
+func TestServerGuard(t *testing.T) {
+	h := newServer(filepath.Join(t.TempDir(), "todo.json"), "", "", "192.168.1.5:8080", nil)
+
+	testCases := []struct {
+		name        string
+		method      string
+		host        string
+		origin      string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"Localhost", "GET", "localhost:8080", "", "", "", http.StatusOK},
+		{"Loopback", "GET", "127.0.0.1:8080", "", "", "", http.StatusOK},
+		{"BindAddress", "GET", "192.168.1.5:8080", "", "", "", http.StatusOK},
+		{"OtherHost", "GET", "attacker.example:8080", "", "", "", http.StatusForbidden},
+		{"SameOrigin", "GET", "localhost:8080", "http://localhost:8080", "", "", http.StatusOK},
+		{"CrossOrigin", "GET", "localhost:8080", "http://attacker.example", "", "", http.StatusForbidden},
+		{"NullOrigin", "GET", "localhost:8080", "null", "", "", http.StatusForbidden},
+		{"JSONBody", "POST", "localhost:8080", "", "application/json; charset=utf-8", `{"task": "task 1"}`, http.StatusCreated},
+		{"TextBody", "POST", "localhost:8080", "", "text/plain", `{"task": "task 1"}`, http.StatusUnsupportedMediaType},
+		{"MissingContentType", "POST", "localhost:8080", "", "", `{"task": "task 1"}`, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/tasks", strings.NewReader(tc.body))
+			r.Host = tc.host
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.code {
+				t.Errorf("Expected status %d, got %d instead: %s", tc.code, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// tuiMode is what the keys typed in the interactive mode do
+type tuiMode int
+
+const (
+	modeList tuiMode = iota
+	modeSearch
+	modeFilter
+	modeEdit
+	modeAdd
+	modeDelete
+)
+
+const tuiHelp = "j/k move  space done  e edit  a add  d delete  / search  f filter  h hide done  r reload  q quit"
+
+// tui is the state of the interactive mode. It keeps the list read
+// from the file, and every change goes through update, so the file
+// is only locked while it's being changed
+type tui struct {
+	filename string
+	c        config
+	order    todo.Order
+
+	l     *todo.List
+	ids   []int
+	lines []string
+
+	cursor   int
+	top      int
+	page     int
+	mode     tuiMode
+	input    []rune
+	search   string
+	query    string
+	hideDone bool
+	status   string
+	quit     bool
+}
+
+// newTUI reads the ToDo file and returns the interactive mode
+// state for it. The -u, -q and -sort options set the initial view
+func newTUI(filename string, c config) (*tui, error) {
+	order, err := todo.ParseOrder(c.sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tui{filename: filename, c: c, order: order, page: 10, hideDone: c.u, query: c.query}
+	return t, t.reload()
+}
+
+// runTUI runs the interactive mode on the terminal until the user
+// quits it
+func runTUI(filename string, c config) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("Interactive mode needs a terminal")
+	}
+
+	t, err := newTUI(filename, c)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+
+	// use the alternate screen, so the shell is left as it was
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 64)
+	for !t.quit {
+		width, height, err := term.GetSize(out)
+		if err != nil {
+			width, height = 80, 24
+		}
+		io.WriteString(os.Stdout, t.render(width, height))
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			t.handle(k)
+		}
+	}
+
+	return nil
+}
+
+// reload reads the list from the file again
+func (t *tui) reload() error {
+	l, err := load(t.filename, t.c)
+	if err != nil {
+		return err
+	}
+	t.l = l
+	t.refresh()
+	return nil
+}
+
+// refresh updates the shown lines after the list or the filters
+// changed, keeping the cursor on the same item when it's still shown
+func (t *tui) refresh() {
+	current := t.selected()
+
+	filters := []todo.Filter{}
+	if t.hideDone {
+		filters = append(filters, todo.Pending)
+	}
+	if t.query != "" {
+		f, err := todo.ParseQuery(t.query)
+		if err != nil {
+			t.status = err.Error()
+		} else {
+			filters = append(filters, f)
+		}
+	}
+	if t.search != "" {
+		// searching is a text~ query, with the text quoted
+		q := `text~"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(t.search) + `"`
+		if f, err := todo.ParseQuery(q); err == nil {
+			filters = append(filters, f)
+		}
+	}
+
+	var filter todo.Filter
+	if len(filters) > 0 {
+		filter = todo.All(filters...)
+	}
+	t.ids, t.lines = t.l.Lines(filter, t.order)
+	t.selectID(current)
+}
+
+// selectID moves the cursor to the item id if it's shown
+func (t *tui) selectID(id int) {
+	for k, v := range t.ids {
+		if v == id {
+			t.cursor = k
+		}
+	}
+	t.move(0)
+}
+
+// selected returns the ID of the item under the cursor, or 0
+func (t *tui) selected() int {
+	if t.cursor < 0 || t.cursor >= len(t.ids) {
+		return 0
+	}
+	return t.ids[t.cursor]
+}
+
+// move moves the cursor by n lines, staying within the list
+func (t *tui) move(n int) {
+	t.cursor += n
+	if t.cursor >= len(t.ids) {
+		t.cursor = len(t.ids) - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+// change applies a change to the file through update and shows the
+// list saved
+func (t *tui) change(op string, fn func(l *todo.List) error) {
+	l, err := update(t.filename, t.c, op, fn)
+	if err != nil {
+		t.status = err.Error()
+		return
+	}
+	t.l = l
+	t.refresh()
+}
+
+// handle updates the state for the key typed
+func (t *tui) handle(key string) {
+	t.status = ""
+	if key == "ctrl-c" {
+		t.quit = true
+		return
+	}
+
+	switch t.mode {
+	case modeList:
+		t.handleList(key)
+	case modeDelete:
+		id := t.selected()
+		t.mode = modeList
+		if key == "y" || key == "Y" {
+			t.change("delete", func(l *todo.List) error { return l.Trash(id) })
+		}
+	default:
+		t.handleInput(key)
+	}
+}
+
+// handleList handles the keys typed while browsing the list
+func (t *tui) handleList(key string) {
+	id := t.selected()
+
+	switch key {
+	case "q":
+		t.quit = true
+	case "up", "k":
+		t.move(-1)
+	case "down", "j":
+		t.move(1)
+	case "pgup":
+		t.move(-t.page)
+	case "pgdn":
+		t.move(t.page)
+	case "home", "g":
+		t.move(-len(t.ids))
+	case "end", "G":
+		t.move(len(t.ids))
+	case " ", "x":
+		if id != 0 {
+			t.change("complete", func(l *todo.List) error { return toggle(l, id) })
+		}
+	case "enter", "e":
+		if id != 0 {
+			t.mode = modeEdit
+			t.input = []rune(task(t.l, id))
+		}
+	case "a":
+		t.mode, t.input = modeAdd, nil
+	case "d", "delete":
+		if id != 0 {
+			t.mode = modeDelete
+		}
+	case "/":
+		t.mode, t.input = modeSearch, []rune(t.search)
+	case "f":
+		t.mode, t.input = modeFilter, []rune(t.query)
+	case "h":
+		t.hideDone = !t.hideDone
+		t.refresh()
+	case "r":
+		if err := t.reload(); err != nil {
+			t.status = err.Error()
+		}
+	case "esc":
+		t.search, t.query = "", ""
+		t.refresh()
+	}
+}
+
+// handleInput handles the keys typed in the prompt of the search,
+// filter, edit and add modes
+func (t *tui) handleInput(key string) {
+	switch key {
+	case "esc":
+		if t.mode == modeSearch {
+			t.search = ""
+			t.refresh()
+		}
+		t.mode = modeList
+		return
+	case "enter":
+		t.submit()
+		return
+	case "backspace":
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case "ctrl-u":
+		t.input = nil
+	default:
+		if utf8.RuneCountInString(key) != 1 {
+			return
+		}
+		t.input = append(t.input, []rune(key)...)
+	}
+
+	// search as the text is typed
+	if t.mode == modeSearch {
+		t.search = string(t.input)
+		t.refresh()
+	}
+}
+
+// submit applies the text typed in the prompt
+func (t *tui) submit() {
+	mode, text := t.mode, strings.TrimSpace(string(t.input))
+	t.mode = modeList
+
+	switch mode {
+	case modeFilter:
+		t.query = text
+		t.refresh()
+	case modeEdit:
+		id := t.selected()
+		t.change("edit", func(l *todo.List) error { return l.Edit(id, text) })
+	case modeAdd:
+		if text == "" {
+			return
+		}
+		id := 0
+		t.change("add", func(l *todo.List) error {
+			l.Add(text)
+			id = (*l)[len(*l)-1].ID
+			return nil
+		})
+		t.selectID(id)
+	}
+}
+
+// render returns the screen for a terminal of the given size
+func (t *tui) render(width, height int) string {
+	rows := height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	t.page = rows
+
+	// scroll to keep the cursor on the screen
+	if t.cursor < t.top {
+		t.top = t.cursor
+	}
+	if t.cursor >= t.top+rows {
+		t.top = t.cursor - rows + 1
+	}
+
+	header := fmt.Sprintf("%s: %d tasks", t.filename, len(t.ids))
+	if t.hideDone {
+		header += ", pending only"
+	}
+	if t.query != "" {
+		header += ", filter: " + t.query
+	}
+	if t.search != "" {
+		header += ", search: " + t.search
+	}
+
+	lines := []string{"\x1b[1m" + truncate(header, width) + "\x1b[0m"}
+	if len(t.ids) == 0 {
+		lines = append(lines, "No tasks")
+	}
+	for k := t.top; k < len(t.lines) && k < t.top+rows; k++ {
+		line := truncate(t.lines[k], width)
+		if k == t.cursor {
+			// highlight the whole width of the line
+			line = "\x1b[7m" + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < rows+1 {
+		lines = append(lines, "")
+	}
+
+	return "\x1b[H\x1b[2J" + strings.Join(lines, "\r\n") + "\r\n" + truncate(t.prompt(), width)
+}
+
+// prompt returns the bottom line of the screen
+func (t *tui) prompt() string {
+	switch t.mode {
+	case modeSearch:
+		return "/" + string(t.input)
+	case modeFilter:
+		return "filter: " + string(t.input)
+	case modeEdit:
+		return "edit #" + strconv.Itoa(t.selected()) + ": " + string(t.input)
+	case modeAdd:
+		return "add: " + string(t.input)
+	case modeDelete:
+		return "delete #" + strconv.Itoa(t.selected()) + "? [y/N]"
+	}
+
+	if t.status != "" {
+		return t.status
+	}
+	return tuiHelp
+}
+
+// toggle completes the item id, or marks it pending again if it's
+// already done
+func toggle(l *todo.List, id int) error {
+	for _, t := range *l {
+		if t.ID == id && t.Done {
+			return l.Uncomplete(id)
+		}
+	}
+	return l.Complete(id)
+}
+
+// task returns the text of the item id
+func task(l *todo.List, id int) string {
+	for _, t := range *l {
+		if t.ID == id {
+			return t.Task
+		}
+	}
+	return ""
+}
+
+// truncate cuts s to width characters
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// escapeKeys are the escape sequences of the keys used by the
+// interactive mode, in the variants sent by common terminals
+var escapeKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1bOA":  "up",
+	"\x1b[B":  "down",
+	"\x1bOB":  "down",
+	"\x1b[H":  "home",
+	"\x1bOH":  "home",
+	"\x1b[1~": "home",
+	"\x1b[F":  "end",
+	"\x1bOF":  "end",
+	"\x1b[4~": "end",
+	"\x1b[3~": "delete",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdn",
+}
+
+// parseKeys splits the input read from the terminal into keys.
+// Special keys get names like "up" or "enter", other keys are
+// returned as the character typed
+func parseKeys(b []byte) []string {
+	keys := []string{}
+
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) > 2 && (b[1] == '[' || b[1] == 'O'):
+			// the sequence ends with a byte from @ to ~
+			k := 2
+			for k < len(b)-1 && (b[k] < 0x40 || b[k] > 0x7e) {
+				k++
+			}
+			if name, ok := escapeKeys[string(b[:k+1])]; ok {
+				keys = append(keys, name)
+			}
+			b = b[k+1:]
+			continue
+		case b[0] == 0x1b:
+			keys = append(keys, "esc")
+		case b[0] == 0x03:
+			keys = append(keys, "ctrl-c")
+		case b[0] == 0x15:
+			keys = append(keys, "ctrl-u")
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, "enter")
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, "backspace")
+		case b[0] < 0x20:
+			// other control characters aren't used
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+
+	return keys
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cli_tools/todo"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		keys  []string
+	}{
+		{"Letters", "jk", []string{"j", "k"}},
+		{"Arrows", "\x1b[A\x1bOB", []string{"up", "down"}},
+		{"PageKeys", "\x1b[5~\x1b[6~", []string{"pgup", "pgdn"}},
+		{"Escape", "\x1b", []string{"esc"}},
+		{"Control", "\r\x7f\x03\x15", []string{"enter", "backspace", "ctrl-c", "ctrl-u"}},
+		{"Unicode", "é", []string{"é"}},
+		{"Unknown", "\x1b[15~x", []string{"x"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if keys := parseKeys([]byte(tc.input)); !reflect.DeepEqual(keys, tc.keys) {
+				t.Errorf("Expected %q, got %q instead", tc.keys, keys)
+			}
+		})
+	}
+}
+
+// typeKeys sends keys to the interactive mode, text written as
+// "text:..." is typed one character at a time
+func typeKeys(ui *tui, keys ...string) {
+	for _, k := range keys {
+		if strings.HasPrefix(k, "text:") {
+			for _, r := range strings.TrimPrefix(k, "text:") {
+				ui.handle(string(r))
+			}
+			continue
+		}
+		ui.handle(k)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+	l.Add("write report\ncall bob\nbuy milk")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	ui, err := newTUI(filename, config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := func() *todo.List {
+		t.Helper()
+		l, err := load(filename, config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	t.Run("Toggle", func(t *testing.T) {
+		typeKeys(ui, "down", " ")
+		if l := saved(); !(*l)[1].Done {
+			t.Errorf("Expected item 2 to be completed")
+		}
+		typeKeys(ui, " ")
+		if l := saved(); (*l)[1].Done || !(*l)[1].CompletedAt.IsZero() {
+			t.Errorf("Expected item 2 to be pending again")
+		}
+	})
+
+	t.Run("Edit", func(t *testing.T) {
+		typeKeys(ui, "e", "ctrl-u", "text:call alice", "enter")
+		if l := saved(); (*l)[1].Task != "call alice" {
+			t.Errorf("Expected task %q, got %q instead", "call alice", (*l)[1].Task)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		typeKeys(ui, "/", "text:MILK")
+		if !reflect.DeepEqual(ui.ids, []int{3}) {
+			t.Errorf("Expected only item 3 to be shown, got %v", ui.ids)
+		}
+		typeKeys(ui, "esc")
+		if len(ui.ids) != 3 {
+			t.Errorf("Expected %d items after clearing the search, got %d instead", 3, len(ui.ids))
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		typeKeys(ui, "f", "text:id>1", "enter")
+		if !reflect.DeepEqual(ui.ids, []int{2, 3}) {
+			t.Errorf("Expected items 2 and 3 to be shown, got %v", ui.ids)
+		}
+		typeKeys(ui, "f", "text:id>", "enter")
+		if !strings.HasPrefix(ui.status, "Invalid query") {
+			t.Errorf("Expected an invalid query message, got %q", ui.status)
+		}
+		typeKeys(ui, "esc")
+	})
+
+	t.Run("AddDelete", func(t *testing.T) {
+		typeKeys(ui, "a", "text:water plants", "enter")
+		if ui.selected() != 4 {
+			t.Errorf("Expected the added item to be selected, got %d", ui.selected())
+		}
+		typeKeys(ui, "d", "n")
+		if l := saved(); len(*l) != 4 {
+			t.Errorf("Expected %d items, got %d instead", 4, len(*l))
+		}
+		typeKeys(ui, "d", "y")
+		if l := saved(); len(l.Trashed()) != 1 {
+			t.Errorf("Expected the item to be in the trash")
+		}
+		if len(ui.ids) != 3 {
+			t.Errorf("Expected %d items shown, got %d instead", 3, len(ui.ids))
+		}
+	})
+
+	t.Run("Render", func(t *testing.T) {
+		typeKeys(ui, "home")
+		screen := ui.render(40, 10)
+		if !strings.Contains(screen, "\x1b[7m  1: write report") {
+			t.Errorf("Expected the first item to be highlighted, got %q", screen)
+		}
+		if !strings.HasSuffix(screen, tuiHelp[:40]) {
+			t.Errorf("Expected the help line at the bottom, got %q", screen)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package todo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// encrypted files start with a header made of encMagic, the kind of
+// key, the salt of the passphrase and the nonce, followed by the
+// AES-256-GCM sealed data. The header is authenticated with the data
+const (
+	encMagic   = "TODOENC1"
+	saltSize   = 16
+	nonceSize  = 12
+	keySize    = 32
+	headerSize = len(encMagic) + 1 + saltSize + nonceSize
+)
+
+// kinds of keys in the header
+const (
+	kindPassphrase = 'p'
+	kindKeyFile    = 'k'
+)
+
+// argon2id parameters used to derive keys from passphrases, the
+// ones recommended by RFC 9106 for memory-constrained settings
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+var (
+	// ErrEncrypted is returned when reading an encrypted file
+	// without a key
+	ErrEncrypted = errors.New("File is encrypted, a passphrase or key file is needed")
+	// ErrDecrypt is returned when an encrypted file can't be read
+	// with the key given
+	ErrDecrypt = errors.New("Can't decrypt, wrong passphrase or key, or damaged file")
+	// ErrPlain is returned when reading a plain file with a key,
+	// which only happens while encrypting it, see AllowPlain
+	ErrPlain = errors.New("File isn't encrypted, encrypt it before using a key")
+)
+
+// Key is the secret encrypted ToDo files are written with: either
+// a passphrase, stretched with argon2id and a random salt, or the
+// random bytes of a key file. Keys derived from the passphrase are
+// cached, and files sealed by the same Key share one salt, so the
+// passphrase is only stretched once per salt
+type Key struct {
+	kind   byte
+	secret []byte
+	// plain files and journal lines are read as they are
+	plain bool
+
+	mu      sync.Mutex
+	salt    []byte
+	derived map[string][]byte
+}
+
+// NewPassphraseKey returns the Key of a passphrase
+func NewPassphraseKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, errors.New("Empty passphrase")
+	}
+	return &Key{kind: kindPassphrase, secret: []byte(passphrase), derived: map[string][]byte{}}, nil
+}
+
+// ReadKeyFile returns the Key kept in filename, see WriteKeyFile
+func ReadKeyFile(filename string) (*Key, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(secret) != keySize {
+		return nil, fmt.Errorf("Invalid key file %s, expected %d base64 encoded bytes", filename, keySize)
+	}
+
+	return &Key{kind: kindKeyFile, secret: secret, derived: map[string][]byte{}}, nil
+}
+
+// WriteKeyFile creates a key file with a new random key, readable
+// by its owner only, and returns the key. An existing file is
+// never overwritten
+func WriteKeyFile(filename string) (*Key, error) {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeSync(f, []byte(base64.StdEncoding.EncodeToString(secret)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+
+	return &Key{kind: kindKeyFile, secret: secret, derived: map[string][]byte{}}, nil
+}
+
+// AllowPlain returns a copy of the key that reads plain files and
+// journal lines as they are, to encrypt them. Other keys reject them
+func (k *Key) AllowPlain() *Key {
+	return &Key{kind: k.kind, secret: k.secret, plain: true, derived: map[string][]byte{}}
+}
+
+// IsEncrypted reports whether data is an encrypted file
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encMagic))
+}
+
+// seal encrypts data with a random nonce
+func (k *Key) seal(data []byte) ([]byte, error) {
+	k.mu.Lock()
+	if k.salt == nil {
+		k.salt = make([]byte, saltSize)
+		if _, err := rand.Read(k.salt); err != nil {
+			k.mu.Unlock()
+			return nil, err
+		}
+	}
+	salt := k.salt
+	k.mu.Unlock()
+
+	header := make([]byte, 0, headerSize)
+	header = append(append(append(header, encMagic...), k.kind), salt...)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header = append(header, nonce...)
+
+	gcm, err := k.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(header, nonce, data, header), nil
+}
+
+// open decrypts data sealed with the key
+func (k *Key) open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) || len(data) < headerSize {
+		return nil, ErrDecrypt
+	}
+
+	kind := data[len(encMagic)]
+	if kind != k.kind {
+		if kind == kindKeyFile {
+			return nil, fmt.Errorf("%w: it was encrypted with a key file", ErrDecrypt)
+		}
+		return nil, fmt.Errorf("%w: it was encrypted with a passphrase", ErrDecrypt)
+	}
+	salt := data[len(encMagic)+1 : len(encMagic)+1+saltSize]
+	nonce := data[headerSize-nonceSize : headerSize]
+
+	gcm, err := k.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	// keep sealing with the salt of the files read, so their key
+	// doesn't need to be derived again
+	k.mu.Lock()
+	if k.salt == nil {
+		k.salt = append([]byte{}, salt...)
+	}
+	k.mu.Unlock()
+
+	return plain, nil
+}
+
+// cipher returns the AES-GCM cipher of the key for the given salt
+func (k *Key) cipher(salt []byte) (cipher.AEAD, error) {
+	secret := k.secret
+	if k.kind == kindPassphrase {
+		k.mu.Lock()
+		derived, ok := k.derived[string(salt)]
+		if !ok {
+			derived = argon2.IDKey(k.secret, salt, argonTime, argonMemory, argonThreads, keySize)
+			k.derived[string(salt)] = derived
+		}
+		k.mu.Unlock()
+		secret = derived
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readFile returns the content of filename, decrypted with key when
+// it's encrypted. A missing file has no content. Plain files are
+// read as they are without a key, or with a key from AllowPlain
+func readFile(filename string, key *Key) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !IsEncrypted(data) {
+		if key != nil && !key.plain && len(data) > 0 {
+			return nil, fmt.Errorf("%s: %w", filename, ErrPlain)
+		}
+		return data, nil
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s: %w", filename, ErrEncrypted)
+	}
+	plain, err := key.open(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return plain, nil
+}
+
+// writeSealed replaces filename with data, encrypted with key when
+// it isn't nil
+func writeSealed(filename string, data []byte, key *Key) error {
+	if key != nil {
+		sealed, err := key.seal(data)
+		if err != nil {
+			return err
+		}
+		data = sealed
+	}
+
+	return writeFile(filename, data, 0644)
+}
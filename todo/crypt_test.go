@@ -0,0 +1,187 @@
+package todo_test
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEncryptedList tests saving and reading a list encrypted with
+// a passphrase
+func TestEncryptedList(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	key, err := todo.NewPassphraseKey("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l1 := todo.List{}
+	l1.Add("call ACME Corp")
+	if err := l1.SaveWithKey(filename, key); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !todo.IsEncrypted(data) || bytes.Contains(data, []byte("ACME")) {
+		t.Fatalf("Expected the file to be encrypted, got %q", data)
+	}
+
+	if err := (&todo.List{}).Get(filename); !errors.Is(err, todo.ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted reading without a key, got %v", err)
+	}
+	wrong, _ := todo.NewPassphraseKey("wrong horse")
+	if err := (&todo.List{}).GetWithKey(filename, wrong); !errors.Is(err, todo.ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt with a wrong passphrase, got %v", err)
+	}
+
+	// a new key from the same passphrase reads the file
+	again, _ := todo.NewPassphraseKey("correct horse")
+	l2 := todo.List{}
+	if err := l2.GetWithKey(filename, again); err != nil {
+		t.Fatal(err)
+	}
+	if l2.String() != l1.String() {
+		t.Errorf("Expected %q, got %q instead", l1.String(), l2.String())
+	}
+
+	// the data is authenticated
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&todo.List{}).GetWithKey(filename, key); !errors.Is(err, todo.ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for a changed file, got %v", err)
+	}
+}
+
+// TestKeyFile tests encrypting a todo.txt store with a key file
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "todo.key")
+	if _, err := todo.WriteKeyFile(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := todo.WriteKeyFile(keyFile); err == nil {
+		t.Error("Expected an error overwriting a key file")
+	}
+	key, err := todo.ReadKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "todo.txt")
+	store, err := todo.NewStoreWithKey(filename, "", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l1 := todo.List{}
+	l1.Add("renew cert +ops")
+	if err := store.Save(&l1); err != nil {
+		t.Fatal(err)
+	}
+	l2 := todo.List{}
+	if err := store.Load(&l2); err != nil {
+		t.Fatal(err)
+	}
+	if l2.String() != l1.String() {
+		t.Errorf("Expected %q, got %q instead", l1.String(), l2.String())
+	}
+
+	passphrase, _ := todo.NewPassphraseKey("secret")
+	if err := (&todo.List{}).GetWithKey(filename, passphrase); !errors.Is(err, todo.ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt with a passphrase, got %v", err)
+	}
+	if _, err := todo.NewStoreWithKey(filepath.Join(dir, "todo.db"), "", key); err == nil {
+		t.Error("Expected an error encrypting a db store")
+	}
+}
+
+// TestEncryptedJournal tests encrypting, reading and decrypting
+// a journal
+func TestEncryptedJournal(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json.journal")
+	key, _ := todo.NewPassphraseKey("secret")
+
+	j, err := todo.OpenJournalWithKey(filename, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	l.Add("call ACME Corp")
+	j.Record("add", todo.List{}, l)
+	if err := j.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("ACME")) {
+		t.Errorf("Expected the journal to be encrypted, got %q", data)
+	}
+	if _, err := todo.OpenJournal(filename); !errors.Is(err, todo.ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted reading without a key, got %v", err)
+	}
+
+	j, err = todo.OpenJournalWithKey(filename, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Rekey(nil); err != nil {
+		t.Fatal(err)
+	}
+	j, err = todo.OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(&l, 1); err != nil {
+		t.Fatal(err)
+	}
+	if out := l.String(); out != "" {
+		t.Errorf("Expected the add to be undone, got %q", out)
+	}
+}
+
+// TestPlainWithKey tests that plain files and journals are only
+// read with a key when it allows it, to encrypt them
+func TestPlainWithKey(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+	key, _ := todo.NewPassphraseKey("secret")
+
+	l := todo.List{}
+	l.Add("call ACME Corp")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	j, err := todo.OpenJournal(filename + ".journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Record("add", todo.List{}, l)
+	if err := j.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&todo.List{}).GetWithKey(filename, key); !errors.Is(err, todo.ErrPlain) {
+		t.Errorf("Expected ErrPlain reading a plain file with a key, got %v", err)
+	}
+	if _, err := todo.OpenJournalWithKey(filename+".journal", key); !errors.Is(err, todo.ErrPlain) {
+		t.Errorf("Expected ErrPlain reading a plain journal with a key, got %v", err)
+	}
+
+	plain := key.AllowPlain()
+	if err := (&todo.List{}).GetWithKey(filename, plain); err != nil {
+		t.Errorf("Expected the plain file to be read, got %v", err)
+	}
+	if _, err := todo.OpenJournalWithKey(filename+".journal", plain); err != nil {
+		t.Errorf("Expected the plain journal to be read, got %v", err)
+	}
+}
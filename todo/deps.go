@@ -0,0 +1,290 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrOpenSubtasks is returned when completing an item whose
+// subtasks aren't all done
+var ErrOpenSubtasks = errors.New("Item has open subtasks")
+
+// SetParent makes the item id a subtask of the item parent.
+// A parent of 0 makes it a top-level item again
+func (l *List) SetParent(id, parent int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	if parent != 0 {
+		if _, err := l.visible(parent); err != nil {
+			return err
+		}
+		// make sure id isn't above the new parent
+		if parent == id || l.hasAncestor(parent, id) {
+			return fmt.Errorf("Item %d can't be a subtask of itself or of its subtasks", id)
+		}
+	}
+
+	(*l)[k].Parent = parent
+	return nil
+}
+
+// Block records that the item id can't be worked on until the
+// item by is done
+func (l *List) Block(id, by int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+	if _, err := l.visible(by); err != nil {
+		return err
+	}
+
+	if id == by || l.dependsOn(by, id) {
+		return fmt.Errorf("Item %d can't be blocked by %d: items would block each other", id, by)
+	}
+
+	for _, b := range (*l)[k].BlockedBy {
+		if b == by {
+			return nil
+		}
+	}
+	(*l)[k].BlockedBy = append(append([]int{}, (*l)[k].BlockedBy...), by)
+
+	return nil
+}
+
+// Unblock removes the "blocked by" link from the item id to by
+func (l *List) Unblock(id, by int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	(*l)[k].BlockedBy = removeID((*l)[k].BlockedBy, by)
+	return nil
+}
+
+// CompleteWithSubtasks completes the item id along with all its
+// open subtasks
+func (l *List) CompleteWithSubtasks(id int) error {
+	if _, err := l.visible(id); err != nil {
+		return err
+	}
+
+	return l.completeTree(id, map[int]bool{})
+}
+
+// completeTree completes the item id after its open subtasks. The
+// items seen already aren't walked again, so a loop of parents ends
+// with an error instead of going on forever
+func (l *List) completeTree(id int, seen map[int]bool) error {
+	seen[id] = true
+	for _, c := range l.openSubtasks(id) {
+		if seen[c] {
+			continue
+		}
+		if err := l.completeTree(c, seen); err != nil {
+			return err
+		}
+	}
+
+	return l.Complete(id)
+}
+
+// Unblocked returns a Filter keeping the pending items that can be
+// worked on: the items they are blocked by and their subtasks are
+// all done
+func (l *List) Unblocked() Filter {
+	open := map[int]bool{}
+	openChildren := map[int]bool{}
+	for _, t := range *l {
+		if !t.Done && !t.hidden() {
+			open[t.ID] = true
+			if t.Parent != 0 {
+				openChildren[t.Parent] = true
+			}
+		}
+	}
+
+	return func(t item) bool {
+		if t.Done || openChildren[t.ID] {
+			return false
+		}
+		for _, b := range t.BlockedBy {
+			if open[b] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// openSubtasks returns the IDs of the direct subtasks of id
+// which aren't done
+func (l *List) openSubtasks(id int) []int {
+	ids := []int{}
+	for _, t := range *l {
+		if t.Parent == id && !t.Done && !t.trashed() {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// parentOf returns the parent of the item id, or 0
+func (l *List) parentOf(id int) int {
+	k, err := l.index(id)
+	if err != nil {
+		return 0
+	}
+	return (*l)[k].Parent
+}
+
+// hasAncestor reports whether anc is the parent of the item id,
+// directly or through its parents. Loops of parents, only possible
+// in edited or merged files, end the walk
+func (l *List) hasAncestor(id, anc int) bool {
+	seen := map[int]bool{}
+	for p := l.parentOf(id); p != 0 && !seen[p]; p = l.parentOf(p) {
+		if p == anc {
+			return true
+		}
+		seen[p] = true
+	}
+	return false
+}
+
+// dependsOn reports whether the item id is blocked by target,
+// directly or through the items blocking it
+func (l *List) dependsOn(id, target int) bool {
+	seen := map[int]bool{}
+	stack := []int{id}
+
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == target {
+			return true
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+
+		if k, err := l.index(cur); err == nil {
+			stack = append(stack, (*l)[k].BlockedBy...)
+		}
+	}
+
+	return false
+}
+
+// unlink removes the references other items hold to the item id:
+// its subtasks become top-level items and the items it blocked
+// aren't blocked by it anymore
+func (l *List) unlink(id int) {
+	for k, t := range *l {
+		if t.Parent == id {
+			(*l)[k].Parent = 0
+		}
+		if containsID(t.BlockedBy, id) {
+			(*l)[k].BlockedBy = removeID(t.BlockedBy, id)
+		}
+	}
+}
+
+// tree reorders the indexes of a view so subtasks follow their
+// parent, and returns the depth of each item. Items whose parent
+// isn't part of the view are shown at the top level
+func (l *List) tree(idx []int) ([]int, []int) {
+	ls := *l
+	inView := map[int]bool{}
+	for _, k := range idx {
+		inView[ls[k].ID] = true
+	}
+
+	children := map[int][]int{}
+	roots := []int{}
+	for _, k := range idx {
+		if p := ls[k].Parent; p != 0 && inView[p] {
+			children[p] = append(children[p], k)
+		} else {
+			roots = append(roots, k)
+		}
+	}
+
+	order := make([]int, 0, len(idx))
+	depth := make([]int, 0, len(idx))
+	var walk func(k, d int)
+	walk = func(k, d int) {
+		order = append(order, k)
+		depth = append(depth, d)
+		for _, c := range children[ls[k].ID] {
+			walk(c, d+1)
+		}
+	}
+	for _, k := range roots {
+		walk(k, 0)
+	}
+
+	// items in a parent loop, only possible in edited files,
+	// have no root and are shown at the top level
+	if len(order) < len(idx) {
+		shown := map[int]bool{}
+		for _, k := range order {
+			shown[k] = true
+		}
+		for _, k := range idx {
+			if !shown[k] {
+				order = append(order, k)
+				depth = append(depth, 0)
+			}
+		}
+	}
+
+	return order, depth
+}
+
+// openBlockers returns the IDs t is blocked by which aren't done
+func (l *List) openBlockers(t item) []string {
+	ids := []string{}
+	for _, b := range t.BlockedBy {
+		if k, err := l.index(b); err == nil && !(*l)[k].Done && !(*l)[k].trashed() {
+			ids = append(ids, strconv.Itoa(b))
+		}
+	}
+	return ids
+}
+
+// blockedSuffix returns the text shown after a blocked task
+func (l *List) blockedSuffix(t item) string {
+	if ids := l.openBlockers(t); len(ids) > 0 {
+		return fmt.Sprintf(" (blocked by %s)", strings.Join(ids, ", "))
+	}
+	return ""
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeID returns a copy of ids without id, or nil if it's empty
+func removeID(ids []int, id int) []int {
+	var out []int
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSubtasks tests the subtask tree and completing parents
+func TestSubtasks(t *testing.T) {
+	l := todo.List{}
+	l.Add("release\nwrite notes\nproofread notes\ntag version\nunrelated")
+
+	for id, parent := range map[int]int{2: 1, 3: 2, 4: 1} {
+		if err := l.SetParent(id, parent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.SetParent(1, 3); err == nil {
+		t.Errorf("Expected error making a parent loop, got nil")
+	}
+
+	expected := "  1: release\n    2: write notes\n      3: proofread notes\n    4: tag version\n  5: unrelated\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if err := l.Complete(1); !errors.Is(err, todo.ErrOpenSubtasks) {
+		t.Errorf("Expected ErrOpenSubtasks, got %v instead", err)
+	}
+	if err := l.CompleteWithSubtasks(2); err != nil {
+		t.Fatal(err)
+	}
+	if !l[1].Done || !l[2].Done || l[0].Done {
+		t.Errorf("Expected items 2 and 3 only to be completed")
+	}
+	if err := l.CompleteWithSubtasks(1); err != nil {
+		t.Fatal(err)
+	}
+	if !l[0].Done || !l[3].Done {
+		t.Errorf("Expected items 1 and 4 to be completed")
+	}
+
+	// deleting a parent makes its subtasks top-level items
+	if err := l.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if l[1].ID != 3 || l[1].Parent != 0 {
+		t.Errorf("Expected item 3 to be top-level, got parent %d", l[1].Parent)
+	}
+}
+
+// TestParentLoop tests that the walks over parents end on a loop
+// of parents, as found in edited files
+func TestParentLoop(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "todo.json")
+	data := `{"Version": 2, "Items": [
+		{"ID": 1, "Task": "first", "Parent": 2, "Done": true},
+		{"ID": 2, "Task": "second", "Parent": 1, "Done": true},
+		{"ID": 3, "Task": "third"}
+	]}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	if err := l.Get(file); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.SetParent(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetParent(1, 3); err == nil {
+		t.Errorf("Expected error making a parent loop, got nil")
+	}
+	if err := l.Uncomplete(1); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Done || l[1].Done {
+		t.Errorf("Expected the items of the loop to be reopened")
+	}
+	if err := l.CompleteWithSubtasks(1); !errors.Is(err, todo.ErrOpenSubtasks) {
+		t.Errorf("Expected ErrOpenSubtasks, got %v instead", err)
+	}
+}
+
+// TestBlockedBy tests blocking items and the unblocked filter
+func TestBlockedBy(t *testing.T) {
+	l := todo.List{}
+	l.Add("deploy\nbuild\ntest\nparent\nchild")
+
+	if err := l.Block(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Block(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Block(2, 1); err == nil {
+		t.Errorf("Expected error blocking items by each other, got nil")
+	}
+	if err := l.Block(3, 3); err == nil {
+		t.Errorf("Expected error blocking an item by itself, got nil")
+	}
+	if err := l.SetParent(5, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "  1: deploy (blocked by 2, 3)\n"
+	if out := l.String(); !strings.HasPrefix(out, expected) {
+		t.Errorf("Expected %q to start with %q", out, expected)
+	}
+
+	expected = "  2: build\n  3: test\n  5: child\n"
+	if out := render(t, &l, "text", l.Unblocked(), todo.ByIndex); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if err := l.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unblock(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	expected = "  1: deploy\n  3: test\n  5: child\n"
+	if out := render(t, &l, "text", l.Unblocked(), todo.ByIndex); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	// deleting a blocking item unblocks the items it blocked
+	if err := l.Block(3, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(5); err != nil {
+		t.Fatal(err)
+	}
+	if len(l[2].BlockedBy) != 0 {
+		t.Errorf("Expected item 3 to be unblocked, got %v", l[2].BlockedBy)
+	}
+}
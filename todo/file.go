@@ -0,0 +1,131 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockTimeout is how long LockFile waits for another process
+// to release the lock when advisory locks aren't available
+const lockTimeout = 10 * time.Second
+
+// Lock is an advisory lock held on a ToDo file. It is kept on a
+// separate filename.lock file, since saving replaces the ToDo file
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// LockFile takes an exclusive lock on the given ToDo file, waiting
+// for other processes holding it. Hold the lock from Get until Save
+// so concurrent read-modify-write cycles don't lose changes
+func LockFile(filename string) (*Lock, error) {
+	path, err := resolve(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lk := &Lock{path: path + ".lock"}
+	if err := lk.lock(); err != nil {
+		return nil, fmt.Errorf("Cannot lock %s: %w", filename, err)
+	}
+
+	return lk, nil
+}
+
+// Unlock releases the lock
+func (lk *Lock) Unlock() error {
+	return lk.unlock()
+}
+
+// Backup keeps a copy of the current version of the ToDo file as
+// filename.bak, with the same mode. It does nothing if the file
+// doesn't exist yet
+func Backup(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	path, err := resolve(filename)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path+".bak", data, fi.Mode().Perm())
+}
+
+// writeFile replaces filename with data. The data is written to a
+// temporary file in the same directory, synced to disk and renamed
+// over filename, so a crash never leaves a truncated file behind.
+// An existing file keeps its mode, perm is only used for new ones
+func writeFile(filename string, data []byte, perm os.FileMode) error {
+	path, err := resolve(filename)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := writeSync(tmp, data, perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// writeSync writes data to f, flushes it to disk and closes f
+func writeSync(f *os.File, data []byte, perm os.FileMode) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// resolve follows filename if it is a symbolic link, so saving
+// replaces the file it points to instead of the link itself
+func resolve(filename string) (string, error) {
+	path, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return filename, nil
+		}
+		return "", err
+	}
+
+	return path, nil
+}
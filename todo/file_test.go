@@ -0,0 +1,117 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSaveBackup tests that Save replaces the file without leaving
+// temporary files and that Backup keeps the previous version
+func TestSaveBackup(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	l := todo.List{}
+	l.Add("New Task 1")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Add("New Task 2")
+	if err := todo.Backup(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files in %s, got %d instead", dir, len(files))
+	}
+
+	old := todo.List{}
+	if err := old.Get(filename + ".bak"); err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 1 {
+		t.Errorf("Expected %d items in backup, got %d instead", 1, len(old))
+	}
+}
+
+// TestSaveKeepsMode tests that saving and backing up a file keep
+// its mode
+func TestSaveKeepsMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	l := todo.List{}
+	l.Add("New Task 1")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filename, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Add("New Task 2")
+	if err := todo.Backup(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{filename, filename + ".bak"} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode %v for %s, got %v instead", os.FileMode(0600), name, fi.Mode().Perm())
+		}
+	}
+}
+
+// TestLockFile tests that a second lock waits until the first
+// one is released
+func TestLockFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	lk, err := todo.LockFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		lk2, err := todo.LockFile(filename)
+		if err != nil {
+			t.Error(err)
+		} else {
+			lk2.Unlock()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("Second lock acquired while the first one was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := lk.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Second lock not acquired after the first one was released")
+	}
+}
@@ -1,3 +1,11 @@
 module cli_tools/todo
 
 go 1.17
+
+require (
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/crypto v0.5.0
+	golang.org/x/term v0.4.0
+)
+
+require golang.org/x/sys v0.4.0 // indirect
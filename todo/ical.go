@@ -0,0 +1,213 @@
+package todo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTime is the layout of UTC date-time values in iCalendar
+const icsTime = "20060102T150405Z"
+
+// WriteICS writes the list as a single iCalendar (RFC 5545) file,
+// with one VTODO entry per task
+func (l *List) WriteICS(w io.Writer) error {
+	now := time.Now()
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//cli_tools//todo//EN"}
+	for _, t := range *l {
+		if t.hidden() {
+			continue
+		}
+		lines = append(lines, t.vtodo(now)...)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	return writeICSLines(w, lines)
+}
+
+// WriteICSDir writes every task of the list as a separate iCalendar
+// file in dir, named after the UID of the task. Tasks keep the same
+// UID on every export, so importing the files again updates the
+// calendar entries instead of duplicating them
+func (l *List) WriteICSDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, t := range *l {
+		if t.hidden() {
+			continue
+		}
+		single := List{t}
+
+		var buf strings.Builder
+		if err := single.WriteICS(&buf); err != nil {
+			return err
+		}
+		name := filepath.Join(dir, t.uid()+".ics")
+		if err := writeFile(name, []byte(buf.String()), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uid returns the iCalendar UID of the item, built from its ID and
+// creation time so it doesn't change between exports
+func (t item) uid() string {
+	return fmt.Sprintf("todo-%d-%s", t.ID, t.CreatedAt.UTC().Format(icsTime))
+}
+
+// vtodo returns the lines of the VTODO entry for the item,
+// exported at the time now
+func (t item) vtodo(now time.Time) []string {
+	modified := t.modified()
+
+	lines := []string{
+		"BEGIN:VTODO",
+		"UID:" + t.uid(),
+		"DTSTAMP:" + now.UTC().Format(icsTime),
+		"CREATED:" + t.CreatedAt.UTC().Format(icsTime),
+		"LAST-MODIFIED:" + modified.UTC().Format(icsTime),
+		// the revision grows with every change, as the seconds
+		// from the creation to the last one
+		fmt.Sprintf("SEQUENCE:%d", int64(modified.Sub(t.CreatedAt)/time.Second)),
+		"SUMMARY:" + icsEscape(t.Task),
+	}
+
+	if t.Done {
+		lines = append(lines, "STATUS:COMPLETED", "COMPLETED:"+t.CompletedAt.UTC().Format(icsTime))
+	} else {
+		lines = append(lines, "STATUS:NEEDS-ACTION")
+	}
+	if t.Priority != "" {
+		// priorities A to I map to 1 (highest) to 9
+		p := int(t.Priority[0]-'A') + 1
+		if p > 9 {
+			p = 9
+		}
+		lines = append(lines, fmt.Sprintf("PRIORITY:%d", p))
+	}
+	if !t.Due.IsZero() {
+		due := t.Due.Format("20060102")
+		if t.Recur != nil {
+			lines = append(lines, "DTSTART;VALUE=DATE:"+due)
+		}
+		lines = append(lines, "DUE;VALUE=DATE:"+due)
+	}
+	if t.Recur != nil {
+		lines = append(lines, "RRULE:"+t.Recur.rrule())
+	}
+	if len(t.Notes) > 0 {
+		notes := []string{}
+		for _, n := range t.Notes {
+			notes = append(notes, n.Text)
+		}
+		lines = append(lines, "DESCRIPTION:"+icsEscape(strings.Join(notes, "\n\n")))
+	}
+
+	categories := []string{}
+	for _, p := range t.Projects {
+		categories = append(categories, icsEscape("+"+p))
+	}
+	for _, c := range t.Contexts {
+		categories = append(categories, icsEscape("@"+c))
+	}
+	if len(categories) > 0 {
+		lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+
+	return append(lines, "END:VTODO")
+}
+
+// modified returns the last time the item changed that is known:
+// when it was created, completed, annotated or worked on
+func (t item) modified() time.Time {
+	last := t.CreatedAt
+	later := func(ts time.Time) {
+		if ts.After(last) {
+			last = ts
+		}
+	}
+
+	if t.Done {
+		later(t.CompletedAt)
+	}
+	for _, n := range t.Notes {
+		later(n.Time)
+	}
+	for _, i := range t.Intervals {
+		later(i.Start)
+		later(i.Stop)
+	}
+
+	return last
+}
+
+// rrule returns the recurrence as an iCalendar RRULE value
+func (r *Recurrence) rrule() string {
+	switch r.Kind {
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return "FREQ=WEEKLY"
+		}
+		days := []string{}
+		for _, d := range r.Weekdays {
+			days = append(days, strings.ToUpper(weekdays[d][:2]))
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",")
+	case Monthly:
+		// months without the day repeat on their last one, as in
+		// Next, which is the last of the days from 28 to Day they have
+		switch {
+		case r.Day <= 28:
+			return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", r.Day)
+		case r.Day == 31:
+			return "FREQ=MONTHLY;BYMONTHDAY=-1"
+		}
+		days := []string{}
+		for d := 28; d <= r.Day; d++ {
+			days = append(days, strconv.Itoa(d))
+		}
+		return "FREQ=MONTHLY;BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+	case Every:
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", r.Interval)
+	}
+
+	return "FREQ=DAILY"
+}
+
+// icsEscape escapes the characters with a meaning in iCalendar text
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLines writes the lines ending with CRLF, folding the
+// ones longer than 75 octets as RFC 5545 requires
+func writeICSLines(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		for len(line) > 75 {
+			// don't split multi-byte characters
+			k := 75
+			for !utf8.RuneStart(line[k]) {
+				k--
+			}
+			if _, err := io.WriteString(w, line[:k]+"\r\n"); err != nil {
+				return err
+			}
+			// continuation lines start with a space
+			line = " " + line[k:]
+		}
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
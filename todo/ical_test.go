@@ -0,0 +1,143 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWriteICS tests exporting the list as iCalendar VTODO entries
+func TestWriteICS(t *testing.T) {
+	l := todo.List{}
+	l.Add("deploy; release, v2 +work\nrenew cert")
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(2, "B"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDue(2, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := todo.ParseRecurrence("weekly:mon,fri")
+	if err := l.SetRecurrence(2, r); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	var buf strings.Builder
+	if err := l.WriteICS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	// the entries are stamped with the export time
+	for _, line := range strings.Split(out, "\r\n") {
+		if !strings.HasPrefix(line, "DTSTAMP:") {
+			continue
+		}
+		stamp, err := time.Parse("20060102T150405Z", strings.TrimPrefix(line, "DTSTAMP:"))
+		if err != nil || stamp.Before(before) {
+			t.Errorf("Expected DTSTAMP at the export time, got %q", line)
+		}
+	}
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"SUMMARY:deploy\\; release\\, v2 +work\r\n",
+		"STATUS:COMPLETED\r\nCOMPLETED:" + l[0].CompletedAt.UTC().Format("20060102T150405Z") + "\r\n",
+		"CATEGORIES:+work\r\n",
+		"LAST-MODIFIED:" + l[0].CompletedAt.UTC().Format("20060102T150405Z") + "\r\n",
+		"CREATED:" + l[1].CreatedAt.UTC().Format("20060102T150405Z") + "\r\n",
+		"LAST-MODIFIED:" + l[1].CreatedAt.UTC().Format("20060102T150405Z") + "\r\nSEQUENCE:0\r\n",
+		"STATUS:NEEDS-ACTION\r\nPRIORITY:2\r\n",
+		"DUE;VALUE=DATE:20260301\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,FR\r\n",
+		"END:VTODO\r\nEND:VCALENDAR\r\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got %q", e, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VTODO"); n != 2 {
+		t.Errorf("Expected %d VTODO entries, got %d instead", 2, n)
+	}
+
+	// long lines are folded at 75 octets
+	l.Add(strings.Repeat("long task ", 20))
+	buf.Reset()
+	if err := l.WriteICS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+// TestMonthlyRRule tests that monthly rules on days some months
+// don't have repeat on the last day of those months
+func TestMonthlyRRule(t *testing.T) {
+	rules := map[string]string{
+		"monthly:15": "RRULE:FREQ=MONTHLY;BYMONTHDAY=15\r\n",
+		"monthly:28": "RRULE:FREQ=MONTHLY;BYMONTHDAY=28\r\n",
+		"monthly:29": "RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29;BYSETPOS=-1\r\n",
+		"monthly:30": "RRULE:FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1\r\n",
+		"monthly:31": "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1\r\n",
+	}
+	for rule, expected := range rules {
+		l := todo.List{}
+		l.Add("pay rent")
+		r, err := todo.ParseRecurrence(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.SetRecurrence(1, r); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf strings.Builder
+		if err := l.WriteICS(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %s to export %q, got %q", rule, expected, buf.String())
+		}
+	}
+}
+
+// TestWriteICSDir tests that exporting to a directory twice keeps
+// one file per task with the same UID
+func TestWriteICSDir(t *testing.T) {
+	dir := t.TempDir()
+
+	l := todo.List{}
+	l.Add("task 1\ntask 2")
+	for i := 0; i < 2; i++ {
+		if err := l.WriteICSDir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.ics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected %d files, got %d instead", 2, len(files))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := strings.TrimSuffix(filepath.Base(files[0]), ".ics")
+	if !strings.Contains(string(data), "UID:"+uid+"\r\n") {
+		t.Errorf("Expected file %s to contain UID %s", files[0], uid)
+	}
+}
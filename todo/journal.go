@@ -0,0 +1,464 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Change records the state of one item before and after an
+// operation. Before is nil for added items and After is nil for
+// deleted ones. Index is the position of the item in the list,
+// before the operation for deletes and after it otherwise
+type Change struct {
+	ID     int
+	Index  int
+	Before *item `json:",omitempty"`
+	After  *item `json:",omitempty"`
+}
+
+// Entry is one operation recorded in the journal. Undo and redo
+// entries point to the entry they revert or replay with Ref
+type Entry struct {
+	Seq     int
+	Time    time.Time
+	User    string
+	Op      string
+	Ref     int      `json:",omitempty"`
+	Changes []Change `json:",omitempty"`
+	// the IDs of the items in list order, only recorded when the
+	// operation moved items around
+	OrderBefore []int `json:",omitempty"`
+	OrderAfter  []int `json:",omitempty"`
+}
+
+// OpArchive is the operation moving items to the archive file. It
+// can't be undone, as the journal doesn't cover the archive
+const OpArchive = "archive"
+
+// Journal is an append-only log of the operations applied to a
+// List. It keeps enough of every item to revert and replay them
+type Journal struct {
+	filename string
+	key      *Key
+	entries  []Entry
+	saved    int
+}
+
+// OpenJournal reads the journal kept in filename. A missing file
+// is an empty journal
+func OpenJournal(filename string) (*Journal, error) {
+	return OpenJournalWithKey(filename, nil)
+}
+
+// OpenJournalWithKey reads the journal like OpenJournal. With a
+// key, every entry is saved as a line of base64 encrypted JSON,
+// and only such entries can be read, unless the key is from
+// AllowPlain, so the journal stays append-only
+func OpenJournalWithKey(filename string, key *Key) (*Journal, error) {
+	j := &Journal{filename: filename, key: key}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return j, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			e, decodeErr := j.decode(line)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("Invalid journal %s: %w", filename, decodeErr)
+			}
+			j.entries = append(j.entries, e)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	j.saved = len(j.entries)
+
+	return j, nil
+}
+
+// decode parses a line of the journal
+func (j *Journal) decode(line []byte) (Entry, error) {
+	e := Entry{}
+	if line[0] == '{' && j.key != nil && !j.key.plain {
+		return e, ErrPlain
+	}
+	if line[0] != '{' {
+		if j.key == nil {
+			return e, ErrEncrypted
+		}
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return e, err
+		}
+		if line, err = j.key.open(sealed); err != nil {
+			return e, err
+		}
+	}
+
+	return e, json.Unmarshal(line, &e)
+}
+
+// encode returns the line of the journal for the entry
+func (j *Journal) encode(e Entry) ([]byte, error) {
+	line, err := json.Marshal(e)
+	if err != nil || j.key == nil {
+		return append(line, '\n'), err
+	}
+
+	sealed, err := j.key.seal(line)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Rekey rewrites the whole journal with key, or in plain text when
+// key is nil
+func (j *Journal) Rekey(key *Key) error {
+	j.key = key
+	if len(j.entries) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, e := range j.entries {
+		line, err := j.encode(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+	}
+	if err := writeFile(j.filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	j.saved = len(j.entries)
+
+	return nil
+}
+
+// Record adds an entry for operation op with the items that
+// differ between the before and after versions of the list, and
+// their order if it changed. Nothing is recorded when the lists
+// are the same
+func (j *Journal) Record(op string, before, after List) {
+	e := Entry{Op: op, Changes: diff(before, after)}
+	if reordered(before, after) {
+		e.OrderBefore, e.OrderAfter = before.ids(), after.ids()
+	}
+	if len(e.Changes) == 0 && e.OrderAfter == nil {
+		return
+	}
+
+	j.append(e)
+}
+
+// Undo reverts the last n operations not undone yet on l and
+// returns how many were reverted
+func (j *Journal) Undo(l *List, n int) (int, error) {
+	count := 0
+	for ; count < n; count++ {
+		done, _ := j.stacks()
+		if len(done) == 0 {
+			break
+		}
+
+		e := j.entries[done[len(done)-1]]
+		if e.Op == OpArchive {
+			return count, fmt.Errorf("Cannot undo %s #%d: the items are in the archive file now", e.Op, e.Seq)
+		}
+		for k := len(e.Changes) - 1; k >= 0; k-- {
+			c := e.Changes[k]
+			if err := l.apply(c.After, c.Before, c.Index); err != nil {
+				return count, fmt.Errorf("Cannot undo %s #%d: %w", e.Op, e.Seq, err)
+			}
+		}
+		l.reorder(e.OrderBefore)
+		j.append(Entry{Op: "undo", Ref: e.Seq})
+	}
+	l.keepIDs(l.nextID())
+
+	if count == 0 {
+		return 0, errors.New("Nothing to undo")
+	}
+	return count, nil
+}
+
+// Redo replays the last n undone operations on l and returns
+// how many were replayed. Recording a new operation discards
+// the undone ones
+func (j *Journal) Redo(l *List, n int) (int, error) {
+	count := 0
+	for ; count < n; count++ {
+		_, undone := j.stacks()
+		if len(undone) == 0 {
+			break
+		}
+
+		e := j.entries[undone[len(undone)-1]]
+		for _, c := range e.Changes {
+			if err := l.apply(c.Before, c.After, c.Index); err != nil {
+				return count, fmt.Errorf("Cannot redo %s #%d: %w", e.Op, e.Seq, err)
+			}
+		}
+		l.reorder(e.OrderAfter)
+		j.append(Entry{Op: "redo", Ref: e.Seq})
+	}
+	l.keepIDs(l.nextID())
+
+	if count == 0 {
+		return 0, errors.New("Nothing to redo")
+	}
+	return count, nil
+}
+
+// Save appends the entries added since the journal was opened
+// to its file
+func (j *Journal) Save() error {
+	if j.saved == len(j.entries) {
+		return nil
+	}
+
+	f, err := os.OpenFile(j.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range j.entries[j.saved:] {
+		line, err := j.encode(e)
+		if err == nil {
+			_, err = f.Write(line)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	j.saved = len(j.entries)
+
+	return f.Close()
+}
+
+// History prints every entry of the journal, showing who changed
+// which items and when
+func (j *Journal) History() string {
+	output := ""
+
+	for _, e := range j.entries {
+		output += fmt.Sprintf("#%d %s %s: %s", e.Seq, e.Time.Format("2006-01-02 15:04:05"), e.User, e.Op)
+		if e.Ref > 0 {
+			output += fmt.Sprintf(" #%d", e.Ref)
+		}
+
+		items := []string{}
+		for _, c := range e.Changes {
+			t := c.After
+			if t == nil {
+				t = c.Before
+			}
+			items = append(items, fmt.Sprintf("%d %q", c.ID, t.Task))
+		}
+		if len(items) > 0 {
+			output += " " + strings.Join(items, ", ")
+		}
+		output += "\n"
+	}
+
+	return output
+}
+
+// append adds a new entry stamped with the next sequence number,
+// the current time and the current user
+func (j *Journal) append(e Entry) {
+	e.Seq = len(j.entries) + 1
+	e.Time = time.Now()
+	e.User = currentUser()
+	j.entries = append(j.entries, e)
+}
+
+// stacks replays the journal and returns the indexes of the
+// entries which are applied and of those which were undone and
+// can be redone, with the most recent ones last
+func (j *Journal) stacks() (done, undone []int) {
+	for k, e := range j.entries {
+		switch e.Op {
+		case "undo":
+			if len(done) > 0 {
+				undone = append(undone, done[len(done)-1])
+				done = done[:len(done)-1]
+			}
+		case "redo":
+			if len(undone) > 0 {
+				done = append(done, undone[len(undone)-1])
+				undone = undone[:len(undone)-1]
+			}
+		default:
+			done = append(done, k)
+			undone = nil
+		}
+	}
+
+	return done, undone
+}
+
+// diff returns the changes turning before into after, matching
+// items by ID. Removed items are left out, Undo and Redo keep
+// the IDs given with keepIDs
+func diff(before, after List) []Change {
+	changes := []Change{}
+	ids := map[int]int{}
+
+	for k, t := range after {
+		if !t.Removed {
+			ids[t.ID] = k
+		}
+	}
+	for k := range before {
+		t := before[k]
+		if _, ok := ids[t.ID]; !ok && !t.Removed {
+			changes = append(changes, Change{ID: t.ID, Index: k, Before: &t})
+		}
+	}
+
+	ids = map[int]int{}
+	for k, t := range before {
+		if !t.Removed {
+			ids[t.ID] = k
+		}
+	}
+	for k := range after {
+		t := after[k]
+		b, ok := ids[t.ID]
+		switch {
+		case t.Removed:
+		case !ok:
+			changes = append(changes, Change{ID: t.ID, Index: k, After: &t})
+		case !reflect.DeepEqual(before[b], t):
+			old := before[b]
+			changes = append(changes, Change{ID: t.ID, Index: k, Before: &old, After: &t})
+		}
+	}
+
+	return changes
+}
+
+// apply replaces the item from with to. A nil from inserts to at
+// index and a nil to removes from
+func (l *List) apply(from, to *item, index int) error {
+	switch {
+	case from == nil:
+		if _, err := l.index(to.ID); err == nil {
+			return fmt.Errorf("Item %d already exists", to.ID)
+		}
+		if index > len(*l) {
+			index = len(*l)
+		}
+		*l = append(*l, item{})
+		copy((*l)[index+1:], (*l)[index:])
+		(*l)[index] = *to
+	case to == nil:
+		return l.Delete(from.ID)
+	default:
+		k, err := l.index(from.ID)
+		if err != nil {
+			return err
+		}
+		(*l)[k] = *to
+	}
+
+	return nil
+}
+
+// ids returns the IDs of the items in list order
+func (l List) ids() []int {
+	ids := make([]int, 0, len(l))
+	for _, t := range l {
+		if !t.Removed {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// reordered reports whether the items found in both lists are in
+// a different order
+func reordered(before, after List) bool {
+	inAfter := map[int]bool{}
+	for _, t := range after {
+		inAfter[t.ID] = !t.Removed
+	}
+	kept := []int{}
+	for _, t := range before {
+		if inAfter[t.ID] {
+			kept = append(kept, t.ID)
+		}
+	}
+
+	k := 0
+	for _, t := range after {
+		if k < len(kept) && t.ID == kept[k] {
+			k++
+		} else if containsID(kept[k:], t.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// reorder sorts the list in the order of ids. Items missing from
+// ids keep their relative order after the others. A nil ids leaves
+// the list unchanged
+func (l *List) reorder(ids []int) {
+	if ids == nil {
+		return
+	}
+
+	pos := map[int]int{}
+	for k, id := range ids {
+		pos[id] = k
+	}
+	rank := func(t item) int {
+		if p, ok := pos[t.ID]; ok {
+			return p
+		}
+		return len(ids)
+	}
+
+	sort.SliceStable(*l, func(a, b int) bool {
+		return rank((*l)[a]) < rank((*l)[b])
+	})
+}
+
+// currentUser returns the name of the user running the program
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,144 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestJournalUndoRedo tests undoing and redoing operations
+// recorded in a journal, across reopening it
+func TestJournalUndoRedo(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json.journal")
+	j, err := todo.OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	steps := []struct {
+		op string
+		do func() error
+	}{
+		{"add", func() error { l.Add("New Task 1\nNew Task 2\nNew Task 3"); return nil }},
+		{"complete", func() error { return l.Complete(2) }},
+		{"delete", func() error { return l.Delete(1) }},
+	}
+	for _, s := range steps {
+		before := append(todo.List{}, l...)
+		if err := s.do(); err != nil {
+			t.Fatal(err)
+		}
+		j.Record(s.op, before, l)
+	}
+	if err := j.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = todo.OpenJournal(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := j.Undo(&l, 2); err != nil || n != 2 {
+		t.Fatalf("Expected 2 operations undone, got %d, %v", n, err)
+	}
+	expected := "  1: New Task 1\n  2: New Task 2\n  3: New Task 3\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if n, err := j.Redo(&l, 5); err != nil || n != 2 {
+		t.Fatalf("Expected 2 operations redone, got %d, %v", n, err)
+	}
+	expected = "X 2: New Task 2\n  3: New Task 3\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if _, err := j.Redo(&l, 1); err == nil {
+		t.Errorf("Expected error with nothing to redo, got nil")
+	}
+
+	if n, err := j.Undo(&l, 5); err != nil || n != 3 {
+		t.Fatalf("Expected 3 operations undone, got %d, %v", n, err)
+	}
+	if out := l.String(); out != "" {
+		t.Errorf("Expected empty list, got %q instead", out)
+	}
+	// undone items keep their IDs too
+	l.Add("New Task 4")
+	if l.String() != "  4: New Task 4\n" {
+		t.Errorf("Expected new task with ID 4, got %q instead", l.String())
+	}
+
+	history := j.History()
+	for _, op := range []string{": add 1 ", ": complete 2 ", ": delete 1 ", ": undo #3", ": redo #2"} {
+		if !strings.Contains(history, op) {
+			t.Errorf("Expected history to contain %q, got %q", op, history)
+		}
+	}
+}
+
+// TestJournalUndoMove tests that operations only changing the
+// order of the items are recorded and can be undone
+func TestJournalUndoMove(t *testing.T) {
+	j, err := todo.OpenJournal(filepath.Join(t.TempDir(), "todo.json.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3")
+	before := append(todo.List{}, l...)
+	if err := l.Move(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	j.Record("move", before, l)
+
+	if _, err := j.Undo(&l, 1); err != nil {
+		t.Fatal(err)
+	}
+	expected := "  1: task 1\n  2: task 2\n  3: task 3\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if _, err := j.Redo(&l, 1); err != nil {
+		t.Fatal(err)
+	}
+	expected = "  3: task 3\n  1: task 1\n  2: task 2\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+}
+
+// TestJournalUndoArchive tests that archiving items can't be
+// undone, as the archive isn't part of the journal
+func TestJournalUndoArchive(t *testing.T) {
+	j, err := todo.OpenJournal(filepath.Join(t.TempDir(), "todo.json.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := todo.List{}
+	l.Add("task 1\ntask 2")
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	before := append(todo.List{}, l...)
+	a := todo.List{}
+	if n := l.Archive(&a, time.Now().Add(time.Minute)); n != 1 {
+		t.Fatalf("Expected %d item archived, got %d instead", 1, n)
+	}
+	j.Record(todo.OpArchive, before, l)
+
+	if _, err := j.Undo(&l, 1); err == nil {
+		t.Errorf("Expected error undoing archive, got nil")
+	}
+	if out := l.String(); out != "  2: task 2\n" {
+		t.Errorf("Expected the archived item to stay out of the list, got %q", out)
+	}
+}
@@ -0,0 +1,38 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package todo
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive flock on the lock file, blocking until
+// other processes release it. The kernel drops the lock if the
+// process dies, so stale locks can't happen
+func (lk *Lock) lock() error {
+	f, err := os.OpenFile(lk.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return err
+	}
+
+	lk.file = f
+	return nil
+}
+
+// unlock releases the flock. The lock file is left in place, so
+// other processes waiting on it keep locking the same file
+func (lk *Lock) unlock() error {
+	if err := syscall.Flock(int(lk.file.Fd()), syscall.LOCK_UN); err != nil {
+		lk.file.Close()
+		return err
+	}
+
+	return lk.file.Close()
+}
@@ -0,0 +1,44 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// lock creates the lock file exclusively, retrying while another
+// process holds it. A lock file left by a crashed process has to be
+// removed by hand, the error returned after lockTimeout says so
+func (lk *Lock) lock() error {
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(lk.path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+		if err == nil {
+			lk.file = f
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock held by another process, remove %s if it is stale", lk.path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// unlock removes the lock file, letting other processes create it
+func (lk *Lock) unlock() error {
+	if err := lk.file.Close(); err != nil {
+		os.Remove(lk.path)
+		return err
+	}
+
+	return os.Remove(lk.path)
+}
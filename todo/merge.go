@@ -0,0 +1,386 @@
+package todo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of merge conflicts
+const (
+	// ConflictEdit is an item changed on both sides in the same fields
+	ConflictEdit = "edit/edit"
+	// ConflictEditDelete is an item changed in ours and deleted in theirs
+	ConflictEditDelete = "edit/delete"
+	// ConflictDeleteEdit is an item deleted in ours and changed in theirs
+	ConflictDeleteEdit = "delete/edit"
+	// ConflictCycle is a link of theirs to a parent or a blocking item
+	// which makes a loop with the links of ours
+	ConflictCycle = "cycle"
+)
+
+// Conflict is an item changed in incompatible ways on both sides
+// of a merge. Fields names the fields of an edit/edit or a cycle
+// conflict. The versions missing from a side are nil
+type Conflict struct {
+	ID     int
+	Kind   string
+	Fields []string `json:",omitempty"`
+	Base   *item    `json:",omitempty"`
+	Ours   *item    `json:",omitempty"`
+	Theirs *item    `json:",omitempty"`
+}
+
+// String describes the conflict and how Merge resolved it
+func (c Conflict) String() string {
+	switch c.Kind {
+	case ConflictEditDelete:
+		return fmt.Sprintf("Item %d: changed in ours, deleted in theirs, kept ours", c.ID)
+	case ConflictDeleteEdit:
+		return fmt.Sprintf("Item %d: deleted in ours, changed in theirs, kept theirs", c.ID)
+	case ConflictCycle:
+		return fmt.Sprintf("Item %d: %s of theirs makes a loop, dropped it", c.ID, strings.Join(c.Fields, ", "))
+	}
+	return fmt.Sprintf("Item %d: %s changed on both sides, kept ours", c.ID, strings.Join(c.Fields, ", "))
+}
+
+// mergeFields are the fields merged as a whole: a field changed
+// on one side only takes that side's value. Notes and intervals
+// are merged separately, as they only grow
+var mergeFields = []struct {
+	name string
+	get  func(t item) interface{}
+	set  func(dst *item, src item)
+}{
+	{"task", func(t item) interface{} { return []interface{}{t.Task, t.Projects, t.Contexts} },
+		func(d *item, s item) { d.Task, d.Projects, d.Contexts = s.Task, s.Projects, s.Contexts }},
+	{"done", func(t item) interface{} { return []interface{}{t.Done, t.CompletedAt, t.Next} },
+		func(d *item, s item) { d.Done, d.CompletedAt, d.Next = s.Done, s.CompletedAt, s.Next }},
+	{"created", func(t item) interface{} { return t.CreatedAt },
+		func(d *item, s item) { d.CreatedAt = s.CreatedAt }},
+	{"priority", func(t item) interface{} { return t.Priority },
+		func(d *item, s item) { d.Priority = s.Priority }},
+	{"due", func(t item) interface{} { return t.Due },
+		func(d *item, s item) { d.Due = s.Due }},
+	{"recur", func(t item) interface{} { return t.Recur },
+		func(d *item, s item) { d.Recur = s.Recur }},
+	{"parent", func(t item) interface{} { return t.Parent },
+		func(d *item, s item) { d.Parent = s.Parent }},
+	{"blocked", func(t item) interface{} { return t.BlockedBy },
+		func(d *item, s item) { d.BlockedBy = s.BlockedBy }},
+	{"deleted", func(t item) interface{} { return t.DeletedAt },
+		func(d *item, s item) { d.DeletedAt = s.DeletedAt }},
+	{"snoozed", func(t item) interface{} { return t.SnoozedUntil },
+		func(d *item, s item) { d.SnoozedUntil = s.SnoozedUntil }},
+}
+
+// Merge merges ours and theirs, two lists changed from base, item
+// by item, and returns the merged list with the conflicts found.
+// Changes made on one side only are kept, as are the items added
+// on either side: when theirs added an item with an ID ours gave
+// to another item, even a deleted one, it gets a new ID. Conflicts are resolved without
+// losing changes: ours wins fields changed on both sides, and an
+// item changed on one side and deleted on the other is kept. Links
+// of theirs making loops of parents or blocking items with the
+// links of ours are dropped
+func Merge(base, ours, theirs List) (List, []Conflict) {
+	// IDs given on any side, even to deleted items, aren't given again
+	next := 1
+	for _, l := range []List{base, ours, theirs} {
+		if id := l.nextID(); id > next {
+			next = id
+		}
+	}
+	oursNext := ours.nextID()
+	base, ours, theirs = base.live(), ours.live(), theirs.live()
+	b, o := byID(base), byID(ours)
+
+	// renumber the items theirs added with IDs ours used for others
+	remap := map[int]int{}
+	for _, t := range theirs {
+		if _, ok := b[t.ID]; ok {
+			continue
+		}
+		mine, ok := o[t.ID]
+		if (ok && !reflect.DeepEqual(mine, t)) || (!ok && t.ID < oursNext) {
+			remap[t.ID] = next
+			next++
+		}
+	}
+	theirs = theirs.renumber(remap)
+	t := byID(theirs)
+
+	conflicts := []Conflict{}
+	merged := map[int]item{}
+	for _, id := range mergeIDs(base, ours, theirs) {
+		bi, inBase := b[id]
+		oi, inOurs := o[id]
+		ti, inTheirs := t[id]
+
+		switch {
+		case inOurs && inTheirs && !inBase:
+			// added on both sides, the same item
+			merged[id] = oi
+		case inOurs && inTheirs:
+			m, fields := mergeItem(bi, oi, ti)
+			merged[id] = m
+			if len(fields) > 0 {
+				conflicts = append(conflicts, Conflict{ID: id, Kind: ConflictEdit, Fields: fields, Base: &bi, Ours: &oi, Theirs: &ti})
+			}
+		case inOurs && !inBase:
+			merged[id] = oi
+		case inTheirs && !inBase:
+			merged[id] = ti
+		case inOurs && !reflect.DeepEqual(bi, oi):
+			merged[id] = oi
+			conflicts = append(conflicts, Conflict{ID: id, Kind: ConflictEditDelete, Base: &bi, Ours: &oi})
+		case inTheirs && !reflect.DeepEqual(bi, ti):
+			merged[id] = ti
+			conflicts = append(conflicts, Conflict{ID: id, Kind: ConflictDeleteEdit, Base: &bi, Theirs: &ti})
+		}
+		// otherwise the item was deleted on one side and left
+		// alone on the other, or deleted on both
+	}
+
+	// keep the order of the side which reordered its items, the
+	// items added on the other side go last
+	first, second := theirs, ours
+	if reordered(base, ours) {
+		first, second = ours, theirs
+	}
+	l := List{}
+	for _, side := range []List{first, second} {
+		for _, it := range side {
+			if m, ok := merged[it.ID]; ok {
+				l = append(l, m)
+				delete(merged, it.ID)
+			}
+		}
+	}
+
+	l.dropDangling()
+	conflicts = append(conflicts, l.dropCycles(o, t)...)
+	l.keepIDs(next)
+	return l, conflicts
+}
+
+// dropCycles drops the parent and blocking links taken from theirs
+// which make loops in the merged list, and returns a conflict for
+// every item changed. A list without loops on its own only gets
+// some when links of both sides are put together, so dropping the
+// links of theirs is enough
+func (l *List) dropCycles(ours, theirs map[int]item) []Conflict {
+	conflicts := []Conflict{}
+	for changed := true; changed; {
+		changed = false
+		for k := range *l {
+			m := (*l)[k]
+			ti, inTheirs := theirs[m.ID]
+			oi, inOurs := ours[m.ID]
+			if !inTheirs {
+				continue
+			}
+
+			fields := []string{}
+			// the links come from theirs when ours doesn't have them
+			if m.Parent != 0 && m.Parent == ti.Parent && (!inOurs || oi.Parent != m.Parent) &&
+				(m.Parent == m.ID || l.hasAncestor(m.Parent, m.ID)) {
+				(*l)[k].Parent = oi.Parent
+				fields = append(fields, "parent")
+			}
+			blockers := m.BlockedBy
+			for _, b := range m.BlockedBy {
+				if containsID(ti.BlockedBy, b) && !containsID(oi.BlockedBy, b) &&
+					(b == m.ID || l.dependsOn(b, m.ID)) {
+					blockers = removeID(blockers, b)
+				}
+			}
+			if len(blockers) != len(m.BlockedBy) {
+				(*l)[k].BlockedBy = blockers
+				fields = append(fields, "blocked")
+			}
+
+			if len(fields) > 0 {
+				changed = true
+				c := Conflict{ID: m.ID, Kind: ConflictCycle, Fields: fields, Theirs: &ti}
+				if inOurs {
+					c.Ours = &oi
+				}
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts
+}
+
+// mergeItem merges the changes made to the item b in o and t, and
+// returns the fields changed differently on both sides
+func mergeItem(b, o, t item) (item, []string) {
+	m := o
+	fields := []string{}
+	for _, f := range mergeFields {
+		bv, ov, tv := f.get(b), f.get(o), f.get(t)
+		switch {
+		case reflect.DeepEqual(ov, tv) || reflect.DeepEqual(tv, bv):
+		case reflect.DeepEqual(ov, bv):
+			f.set(&m, t)
+		default:
+			fields = append(fields, f.name)
+		}
+	}
+
+	m.Notes = mergeNotes(o.Notes, t.Notes)
+	m.Intervals = mergeIntervals(b.Intervals, o.Intervals, t.Intervals)
+	return m, fields
+}
+
+// mergeNotes returns the notes of both sides, sorted by time
+func mergeNotes(o, t []Note) []Note {
+	notes := append([]Note{}, o...)
+	for _, n := range t {
+		found := false
+		for _, m := range o {
+			found = found || reflect.DeepEqual(n, m)
+		}
+		if !found {
+			notes = append(notes, n)
+		}
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Time.Before(notes[j].Time)
+	})
+	return notes
+}
+
+// mergeIntervals returns the intervals of both sides, sorted by
+// start. An interval stopped on theirs side only is taken from it
+func mergeIntervals(b, o, t []Interval) []Interval {
+	find := func(intervals []Interval, start Interval) (int, bool) {
+		for k, i := range intervals {
+			if i.Start.Equal(start.Start) {
+				return k, true
+			}
+		}
+		return -1, false
+	}
+
+	intervals := append([]Interval{}, o...)
+	for _, i := range t {
+		k, ok := find(intervals, i)
+		if !ok {
+			intervals = append(intervals, i)
+			continue
+		}
+		if base, ok := find(b, i); ok && reflect.DeepEqual(intervals[k], b[base]) {
+			intervals[k] = i
+		}
+	}
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+	return intervals
+}
+
+// renumber returns a copy of the list with the IDs in remap changed,
+// along with the links to them
+func (l List) renumber(remap map[int]int) List {
+	if len(remap) == 0 {
+		return l
+	}
+
+	r := make(List, len(l))
+	for k, t := range l {
+		if id, ok := remap[t.ID]; ok {
+			t.ID = id
+		}
+		if id, ok := remap[t.Parent]; ok {
+			t.Parent = id
+		}
+		if id, ok := remap[t.Next]; ok {
+			t.Next = id
+		}
+		blockers := make([]int, 0, len(t.BlockedBy))
+		for _, b := range t.BlockedBy {
+			if id, ok := remap[b]; ok {
+				b = id
+			}
+			blockers = append(blockers, b)
+		}
+		if len(blockers) > 0 {
+			t.BlockedBy = blockers
+		}
+		r[k] = t
+	}
+	return r
+}
+
+// dropDangling removes the links to items missing from the list
+func (l *List) dropDangling() {
+	ids := map[int]bool{}
+	for _, t := range *l {
+		ids[t.ID] = true
+	}
+
+	for k, t := range *l {
+		if t.Parent != 0 && !ids[t.Parent] {
+			(*l)[k].Parent = 0
+		}
+		blockers := []int{}
+		for _, b := range t.BlockedBy {
+			if ids[b] {
+				blockers = append(blockers, b)
+			}
+		}
+		if len(blockers) != len(t.BlockedBy) {
+			if len(blockers) == 0 {
+				blockers = nil
+			}
+			(*l)[k].BlockedBy = blockers
+		}
+	}
+}
+
+// live returns the items of the list, leaving out the removed
+// ones which only keep the IDs of deleted items
+func (l List) live() List {
+	items := List{}
+	for _, t := range l {
+		if !t.Removed {
+			items = append(items, t)
+		}
+	}
+	return items
+}
+
+// byID indexes the items of the list by ID
+func byID(l List) map[int]item {
+	m := make(map[int]item, len(l))
+	for _, t := range l {
+		m[t.ID] = t
+	}
+	return m
+}
+
+// mergeIDs returns the IDs of the items of the three lists, each once
+func mergeIDs(lists ...List) []int {
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, l := range lists {
+		for _, t := range l {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				ids = append(ids, t.ID)
+			}
+		}
+	}
+	return ids
+}
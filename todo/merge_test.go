@@ -0,0 +1,155 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMerge tests merging two lists changed from the same base
+func TestMerge(t *testing.T) {
+	base := todo.List{}
+	base.Add("a\nb\nc\nd")
+
+	ours := append(todo.List{}, base...)
+	theirs := append(todo.List{}, base...)
+	steps := []func() error{
+		func() error { return ours.Complete(1) },
+		func() error { return ours.Edit(2, "b ours") },
+		func() error { return ours.Delete(3) },
+		func() error { return ours.SetPriority(4, "A") },
+		func() error { ours.Add("ours new"); return nil },
+		func() error { return theirs.Edit(1, "a +theirs") },
+		func() error { return theirs.Edit(2, "b theirs") },
+		func() error { return theirs.SetPriority(3, "B") },
+		func() error { theirs.Add("theirs new"); return ours.Annotate(4, "note") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+
+	expected := "X 1: a +theirs\n  2: b ours\n  3: (B) c\n  4: (A) d\n  6: theirs new\n  5: ours new\n"
+	if out := merged.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+	if len(merged[3].Notes) != 1 {
+		t.Errorf("Expected the note of item 4 to be kept, got %v", merged[3].Notes)
+	}
+
+	kinds := []string{}
+	for _, c := range conflicts {
+		kinds = append(kinds, c.String())
+	}
+	expectedKinds := []string{
+		"Item 2: task changed on both sides, kept ours",
+		"Item 3: deleted in ours, changed in theirs, kept theirs",
+	}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected conflicts %q, got %q instead", expectedKinds, kinds)
+	}
+	if conflicts[0].Kind != todo.ConflictEdit || conflicts[1].Ours != nil || conflicts[1].Theirs == nil {
+		t.Errorf("Unexpected conflicts %+v", conflicts)
+	}
+}
+
+// TestMergeDeletes tests that deletes and identical changes merge
+// cleanly, dropping the links to deleted items
+func TestMergeDeletes(t *testing.T) {
+	base := todo.List{}
+	base.Add("parent\nchild\nother")
+	if err := base.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ours := append(todo.List{}, base...)
+	theirs := append(todo.List{}, base...)
+	for _, l := range []*todo.List{&ours, &theirs} {
+		if err := l.Delete(3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// theirs deletes the parent without touching the child, which
+	// ours left alone, so the child becomes top-level
+	theirs = append(todo.List{}, theirs[1:]...)
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+	if expected := "  2: child\n"; merged.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, merged.String())
+	}
+	if merged[0].Parent != 0 {
+		t.Errorf("Expected the link to the deleted parent to be dropped")
+	}
+
+	// the ID of an item ours added and deleted isn't given again
+	ours.Add("ours deleted")
+	if err := ours.Delete(4); err != nil {
+		t.Fatal(err)
+	}
+	theirs.Add("theirs new")
+	merged, _ = todo.Merge(base, ours, theirs)
+	if expected := "  2: child\n  5: theirs new\n"; merged.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, merged.String())
+	}
+	merged.Add("merged new")
+	if expected := "  6: merged new\n"; !strings.HasSuffix(merged.String(), expected) {
+		t.Errorf("Expected %q last, got %q instead", expected, merged.String())
+	}
+}
+
+// TestMergeCycles tests that links making loops once both sides
+// are merged are dropped from theirs and reported
+func TestMergeCycles(t *testing.T) {
+	base := todo.List{}
+	base.Add("a\nb\nc\nd")
+
+	ours := append(todo.List{}, base...)
+	theirs := append(todo.List{}, base...)
+	steps := []func() error{
+		func() error { return ours.SetParent(1, 2) },
+		func() error { return theirs.SetParent(2, 1) },
+		func() error { return ours.Block(3, 4) },
+		func() error { return theirs.Block(4, 3) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, conflicts := todo.Merge(base, ours, theirs)
+
+	kinds := []string{}
+	for _, c := range conflicts {
+		if c.Kind != todo.ConflictCycle {
+			t.Errorf("Expected a cycle conflict, got %+v", c)
+		}
+		kinds = append(kinds, c.String())
+	}
+	expectedKinds := []string{
+		"Item 2: parent of theirs makes a loop, dropped it",
+		"Item 4: blocked of theirs makes a loop, dropped it",
+	}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected conflicts %q, got %q instead", expectedKinds, kinds)
+	}
+
+	if merged[0].Parent != 2 || merged[1].Parent != 0 {
+		t.Errorf("Expected item 1 to stay a subtask of 2, got parents %d and %d",
+			merged[0].Parent, merged[1].Parent)
+	}
+	if len(merged[2].BlockedBy) != 1 || len(merged[3].BlockedBy) != 0 {
+		t.Errorf("Expected item 3 to stay blocked by 4 only, got %v and %v",
+			merged[2].BlockedBy, merged[3].BlockedBy)
+	}
+	if err := merged.CompleteWithSubtasks(2); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Note is a timestamped annotation attached to an item. Its text
+// may span several lines
+type Note struct {
+	Time time.Time
+	Text string
+}
+
+// Annotate attaches a note to the item id. Unlike Add, multi-line
+// text is kept as a single note
+func (l *List) Annotate(id int, text string) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	text = strings.TrimRight(text, " \t\r\n")
+	if strings.TrimSpace(text) == "" {
+		return errors.New("Empty note")
+	}
+
+	notes := append([]Note{}, (*l)[k].Notes...)
+	(*l)[k].Notes = append(notes, Note{Time: time.Now(), Text: text})
+
+	return nil
+}
+
+// notes returns the verbose lines for the notes of an item, with
+// the lines of multi-line notes indented below the first one
+func (t item) notes() string {
+	if len(t.Notes) == 0 {
+		return ""
+	}
+
+	output := "\tNotes:\n"
+	for _, n := range t.Notes {
+		lines := strings.Split(n.Text, "\n")
+		output += fmt.Sprintf("\t  %s: %s\n", n.Time.Format("2006-01-02 15:04"), lines[0])
+		for _, line := range lines[1:] {
+			output += fmt.Sprintf("\t    %s\n", line)
+		}
+	}
+
+	return output
+}
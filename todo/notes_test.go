@@ -0,0 +1,44 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"strings"
+	"testing"
+)
+
+// TestAnnotate tests adding notes to an item
+func TestAnnotate(t *testing.T) {
+	l := todo.List{}
+	l.Add("deploy")
+
+	if err := l.Annotate(1, "first try failed\nretry after the fix\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Annotate(1, "done on staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l[0].Notes) != 2 {
+		t.Fatalf("Expected %d notes, got %d instead", 2, len(l[0].Notes))
+	}
+	if l[0].Notes[0].Text != "first try failed\nretry after the fix" {
+		t.Errorf("Expected multi-line note to be kept, got %q instead", l[0].Notes[0].Text)
+	}
+	if l[0].Notes[0].Time.IsZero() {
+		t.Errorf("Expected note to have a time")
+	}
+
+	out := render(t, &l, "verbose", nil, todo.ByIndex)
+	stamp := l[0].Notes[0].Time.Format("2006-01-02 15:04")
+	expected := "\tNotes:\n\t  " + stamp + ": first try failed\n\t    retry after the fix\n"
+	if !strings.Contains(out, expected) {
+		t.Errorf("Expected verbose output to contain %q, got %q", expected, out)
+	}
+
+	if err := l.Annotate(1, " \n"); err == nil {
+		t.Errorf("Expected error for an empty note")
+	}
+	if err := l.Annotate(2, "note"); err == nil {
+		t.Errorf("Expected error for a missing item")
+	}
+}
@@ -0,0 +1,394 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseQuery parses a query into a Filter. A query compares item
+// fields with values and combines the comparisons with and, or,
+// not and parentheses, for example
+//
+//	done:false and created>2026-01-01 and text~"deploy"
+//
+// Fields are text, done, id, priority, created, completed, due,
+// project, context and tag. Operators are : and = (equal), !=, <,
+// <=, >, >= and ~ (text contains, ignoring case). Dates are written
+// as YYYY-MM-DD and "none" matches an empty priority or date.
+// Values with spaces or operators are written in double quotes
+func ParseQuery(query string) (Filter, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexQuery splits a query into tokens
+func lexQuery(query string) ([]token, error) {
+	tokens := []token{}
+	rs := []rune(query)
+
+	for k := 0; k < len(rs); {
+		r := rs[k]
+		switch {
+		case unicode.IsSpace(r):
+			k++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", k})
+			k++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", k})
+			k++
+		case r == '"':
+			start := k
+			var sb strings.Builder
+			for k++; k < len(rs) && rs[k] != '"'; k++ {
+				if rs[k] == '\\' && k+1 < len(rs) {
+					k++
+				}
+				sb.WriteRune(rs[k])
+			}
+			if k == len(rs) {
+				return nil, fmt.Errorf("Invalid query at position %d: unterminated string", start+1)
+			}
+			tokens = append(tokens, token{tokString, sb.String(), start})
+			k++
+		case strings.ContainsRune(":=!<>~", r):
+			op := string(r)
+			if k+1 < len(rs) && rs[k+1] == '=' && strings.ContainsRune("!<>", r) {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("Invalid query at position %d: expected != or not", k+1)
+			}
+			tokens = append(tokens, token{tokOp, op, k})
+			k += len(op)
+		default:
+			start := k
+			for k < len(rs) && !unicode.IsSpace(rs[k]) && !strings.ContainsRune(`()":=!<>~`, rs[k]) {
+				k++
+			}
+			tokens = append(tokens, token{tokWord, string(rs[start:k]), start})
+		}
+	}
+
+	return append(tokens, token{tokEOF, "end of query", len(rs)}), nil
+}
+
+// queryParser is a recursive descent parser for queries
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword,
+// consuming it if it is
+func (p *queryParser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) errorf(t token, format string, a ...interface{}) error {
+	return fmt.Errorf("Invalid query at position %d: %s", t.pos+1, fmt.Sprintf(format, a...))
+}
+
+// or parses: and ("or" and)*
+func (p *queryParser) or() (Filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t item) bool { return l(t) || right(t) }
+	}
+
+	return left, nil
+}
+
+// and parses: unary ("and" unary)*
+func (p *queryParser) and() (Filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t item) bool { return l(t) && right(t) }
+	}
+
+	return left, nil
+}
+
+// unary parses: "not" unary | "(" or ")" | comparison
+func (p *queryParser) unary() (Filter, error) {
+	if p.keyword("not") {
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(t item) bool { return !f(t) }, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, p.errorf(t, "expected ) instead of %q", t.text)
+		}
+		return f, nil
+	}
+
+	return p.comparison()
+}
+
+// comparison parses: field operator value
+func (p *queryParser) comparison() (Filter, error) {
+	field := p.next()
+	if field.kind != tokWord {
+		return nil, p.errorf(field, "expected a field name instead of %q", field.text)
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, p.errorf(op, "expected an operator after %q instead of %q", field.text, op.text)
+	}
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, p.errorf(value, "expected a value after %s%s instead of %q", field.text, op.text, value.text)
+	}
+
+	switch strings.ToLower(field.text) {
+	case "text", "task":
+		return p.textFilter(op, value)
+	case "done":
+		return p.boolFilter(op, value, func(t item) bool { return t.Done })
+	case "id":
+		return p.intFilter(op, value, func(t item) int { return t.ID })
+	case "priority":
+		return p.priorityFilter(op, value)
+	case "created":
+		return p.dateFilter(op, value, func(t item) time.Time { return t.CreatedAt })
+	case "completed":
+		return p.dateFilter(op, value, func(t item) time.Time { return t.CompletedAt })
+	case "due":
+		return p.dateFilter(op, value, func(t item) time.Time { return t.Due })
+	case "project":
+		return p.tagFilter(op, value, "+")
+	case "context":
+		return p.tagFilter(op, value, "@")
+	case "tag":
+		return p.tagFilter(op, value, "")
+	}
+
+	return nil, p.errorf(field, "unknown field %q, expected text, done, id, priority, created, completed, due, project, context or tag", field.text)
+}
+
+// checkOp returns an error if op isn't one of the allowed operators
+func (p *queryParser) checkOp(op token, field string, allowed ...string) error {
+	for _, a := range allowed {
+		if op.text == a {
+			return nil
+		}
+	}
+	return p.errorf(op, "operator %s can't be used with %s, expected one of %s", op.text, field, strings.Join(allowed, " "))
+}
+
+func (p *queryParser) textFilter(op, value token) (Filter, error) {
+	if err := p.checkOp(op, "text", ":", "=", "!=", "~"); err != nil {
+		return nil, err
+	}
+
+	v := value.text
+	switch op.text {
+	case "~":
+		v = strings.ToLower(v)
+		return func(t item) bool { return strings.Contains(strings.ToLower(t.Task), v) }, nil
+	case "!=":
+		return func(t item) bool { return t.Task != v }, nil
+	}
+	return func(t item) bool { return t.Task == v }, nil
+}
+
+func (p *queryParser) boolFilter(op, value token, get func(item) bool) (Filter, error) {
+	if err := p.checkOp(op, "done", ":", "=", "!="); err != nil {
+		return nil, err
+	}
+
+	v, err := strconv.ParseBool(value.text)
+	if err != nil {
+		return nil, p.errorf(value, "expected true or false instead of %q", value.text)
+	}
+	if op.text == "!=" {
+		v = !v
+	}
+
+	return func(t item) bool { return get(t) == v }, nil
+}
+
+func (p *queryParser) intFilter(op, value token, get func(item) int) (Filter, error) {
+	if err := p.checkOp(op, "id", ":", "=", "!=", "<", "<=", ">", ">="); err != nil {
+		return nil, err
+	}
+
+	v, err := strconv.Atoi(value.text)
+	if err != nil {
+		return nil, p.errorf(value, "expected a number instead of %q", value.text)
+	}
+
+	return func(t item) bool { return compare(get(t), v, op.text) }, nil
+}
+
+func (p *queryParser) priorityFilter(op, value token) (Filter, error) {
+	if err := p.checkOp(op, "priority", ":", "=", "!=", "<", "<=", ">", ">="); err != nil {
+		return nil, err
+	}
+
+	v := strings.ToUpper(value.text)
+	if strings.EqualFold(v, "none") {
+		if op.text != ":" && op.text != "=" && op.text != "!=" {
+			return nil, p.errorf(value, "none can only be compared with : = and !=")
+		}
+		return func(t item) bool { return (t.Priority == "") == (op.text != "!=") }, nil
+	}
+	if len(v) != 1 || v[0] < 'A' || v[0] > 'Z' {
+		return nil, p.errorf(value, "expected a priority from A to Z instead of %q", value.text)
+	}
+
+	// A is the highest priority, so priority>B keeps A
+	return func(t item) bool {
+		if t.Priority == "" {
+			return op.text == "!="
+		}
+		return compare(int(v[0]), int(t.Priority[0]), op.text)
+	}, nil
+}
+
+func (p *queryParser) dateFilter(op, value token, get func(item) time.Time) (Filter, error) {
+	if err := p.checkOp(op, "dates", ":", "=", "!=", "<", "<=", ">", ">="); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(value.text, "none") {
+		if op.text != ":" && op.text != "=" && op.text != "!=" {
+			return nil, p.errorf(value, "none can only be compared with : = and !=")
+		}
+		return func(t item) bool { return get(t).IsZero() == (op.text != "!=") }, nil
+	}
+
+	d, err := time.ParseInLocation(DateLayout, value.text, time.Local)
+	if err != nil {
+		return nil, p.errorf(value, "expected a date as YYYY-MM-DD instead of %q", value.text)
+	}
+
+	// dates are compared by day
+	return func(t item) bool {
+		v := get(t)
+		if v.IsZero() {
+			return op.text == "!="
+		}
+		day := startOfDay(v.In(time.Local))
+		cmp := 0
+		switch {
+		case day.Before(d):
+			cmp = -1
+		case day.After(d):
+			cmp = 1
+		}
+		return compare(cmp, 0, op.text)
+	}, nil
+}
+
+func (p *queryParser) tagFilter(op, value token, prefix string) (Filter, error) {
+	if err := p.checkOp(op, "tags", ":", "=", "!="); err != nil {
+		return nil, err
+	}
+
+	tag := value.text
+	if prefix != "" && !strings.HasPrefix(tag, prefix) {
+		tag = prefix + tag
+	}
+	if len(tag) < 2 || (tag[0] != '+' && tag[0] != '@') {
+		return nil, p.errorf(value, "expected +project or @context instead of %q", value.text)
+	}
+
+	return func(t item) bool { return t.hasTag(tag) == (op.text != "!=") }, nil
+}
+
+// compare applies the comparison operator op to a and b
+func compare(a, b int, op string) bool {
+	switch op {
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return a == b
+}
@@ -0,0 +1,103 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseQuery tests filtering the list with queries
+func TestParseQuery(t *testing.T) {
+	l := todo.List{}
+	l.Add("deploy api +work\nDeploy web +work @office\nbuy milk @shop\nold task")
+	if err := l.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(1, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(3, "C"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDue(3, time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	l[3].CreatedAt = time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{`done:false and text~"deploy"`, "1"},
+		{`text~DEPLOY`, "1 2"},
+		{`done:true or priority:C`, "2 3"},
+		{`created>2021-01-01 and not project:work`, "3"},
+		{`created<2021-01-01`, "4"},
+		{`created>=2021-01-01 and (context:shop or context:office)`, "2 3"},
+		{`priority>B`, "1"},
+		{`priority<=B`, "3"},
+		{`priority:none`, "2 4"},
+		{`due:2026-02-01`, "3"},
+		{`due!=none`, "3"},
+		{`due<2026-03-01 and due>2026-01-01`, "3"},
+		{`tag:+work and id!=1`, "2"},
+		{`text:"buy milk @shop"`, "3"},
+		{`NOT done:false AND id<=2`, "2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			f, err := todo.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			js := render(t, &l, "json", f, todo.ByIndex)
+			items := []struct{ ID int }{}
+			if err := json.Unmarshal([]byte(js), &items); err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []string{}
+			for _, i := range items {
+				ids = append(ids, strconv.Itoa(i.ID))
+			}
+			if got := strings.Join(ids, " "); got != tc.expected {
+				t.Errorf("Expected items %q, got %q instead", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestParseQueryErrors tests the errors reported for invalid queries
+func TestParseQueryErrors(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{`done`, "position 5: expected an operator"},
+		{`done:maybe`, "position 6: expected true or false"},
+		{`size>3`, "position 1: unknown field \"size\""},
+		{`text<abc`, "position 5: operator < can't be used with text"},
+		{`due>tomorrow`, "position 5: expected a date"},
+		{`(done:true`, "position 11: expected )"},
+		{`text~"deploy`, "position 6: unterminated string"},
+		{`done:true done:false`, "position 11: unexpected \"done\""},
+		{`id!3`, "position 3: expected != or not"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := todo.ParseQuery(tc.query)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q instead", tc.expected, err)
+			}
+		})
+	}
+}
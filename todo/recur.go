@@ -0,0 +1,193 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence kinds
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Every   = "every"
+)
+
+// Recurrence describes how often a task repeats. Weekly tasks
+// repeat on the given Weekdays (every week when empty), monthly
+// tasks on the given Day of the month and "every" tasks every
+// Interval days
+type Recurrence struct {
+	Kind     string
+	Interval int            `json:",omitempty"`
+	Weekdays []time.Weekday `json:",omitempty"`
+	Day      int            `json:",omitempty"`
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseRecurrence parses a recurrence rule written as "daily",
+// "weekly", "weekly:mon,thu", "monthly:15" or "every:3" (days)
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	kind, arg := rule, ""
+	if k := strings.Index(rule, ":"); k >= 0 {
+		kind, arg = rule[:k], rule[k+1:]
+	}
+
+	r := &Recurrence{Kind: strings.ToLower(kind)}
+	switch r.Kind {
+	case Daily:
+		if arg != "" {
+			return nil, fmt.Errorf("Invalid recurrence %q: daily takes no argument", rule)
+		}
+	case Weekly:
+		if arg == "" {
+			break
+		}
+		for _, d := range strings.Split(arg, ",") {
+			wd, err := parseWeekday(d)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid recurrence %q: %w", rule, err)
+			}
+			r.Weekdays = append(r.Weekdays, wd)
+		}
+	case Monthly:
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return nil, fmt.Errorf("Invalid recurrence %q: expected a day of the month from 1 to 31", rule)
+		}
+		r.Day = day
+	case Every:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("Invalid recurrence %q: expected a number of days", rule)
+		}
+		r.Interval = n
+	default:
+		return nil, fmt.Errorf("Invalid recurrence %q: expected daily, weekly, monthly or every", rule)
+	}
+
+	return r, nil
+}
+
+// parseWeekday converts a day name, like "mon" or "Monday",
+// into a time.Weekday
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) >= 3 {
+		for k, d := range weekdays {
+			if strings.HasPrefix(name, d) {
+				return time.Weekday(k), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// String returns the rule in the format read by ParseRecurrence
+func (r *Recurrence) String() string {
+	switch r.Kind {
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return Weekly
+		}
+		days := []string{}
+		for _, d := range r.Weekdays {
+			days = append(days, weekdays[d])
+		}
+		return Weekly + ":" + strings.Join(days, ",")
+	case Monthly:
+		return fmt.Sprintf("%s:%d", Monthly, r.Day)
+	case Every:
+		return fmt.Sprintf("%s:%d", Every, r.Interval)
+	}
+
+	return r.Kind
+}
+
+// Next returns the first day the task repeats on after the day
+// of from
+func (r *Recurrence) Next(from time.Time) time.Time {
+	day := startOfDay(from)
+
+	switch r.Kind {
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return day.AddDate(0, 0, 7)
+		}
+		for k := 1; k <= 7; k++ {
+			next := day.AddDate(0, 0, k)
+			for _, wd := range r.Weekdays {
+				if next.Weekday() == wd {
+					return next
+				}
+			}
+		}
+	case Monthly:
+		y, m, d := day.Date()
+		if d >= monthDay(y, m, r.Day) {
+			m++
+		}
+		return time.Date(y, m, monthDay(y, m, r.Day), 0, 0, 0, 0, day.Location())
+	case Every:
+		return day.AddDate(0, 0, r.Interval)
+	}
+
+	return day.AddDate(0, 0, 1)
+}
+
+// monthDay returns day clamped to the length of the month, so
+// tasks repeating on the 31st fall on the last day of shorter months
+func monthDay(y int, m time.Month, day int) int {
+	// day 0 of the next month is the last day of month m
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		return last
+	}
+	return day
+}
+
+// SetRecurrence sets the recurrence rule of a ToDo item. A nil
+// rule stops the item from repeating
+func (l *List) SetRecurrence(id int, r *Recurrence) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	(*l)[k].Recur = r
+	return nil
+}
+
+// repeat appends the next occurrence of the recurring item t,
+// completed at the time done, and returns its ID. The next due date is computed from
+// the due date of t and is always after the day t was completed
+func (l *List) repeat(t item, done time.Time) int {
+	next := t.Due
+	if next.IsZero() {
+		next = done
+	}
+	for {
+		next = t.Recur.Next(next)
+		if next.After(done) {
+			break
+		}
+	}
+
+	id := l.nextID()
+	*l = append(*l, item{
+		ID:        id,
+		Task:      t.Task,
+		CreatedAt: done,
+		Priority:  t.Priority,
+		Due:       next,
+		Projects:  t.Projects,
+		Contexts:  t.Contexts,
+		Recur:     t.Recur,
+	})
+	l.keepIDs(id)
+	return id
+}
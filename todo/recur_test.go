@@ -0,0 +1,99 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"testing"
+	"time"
+)
+
+// TestRecurrenceNext tests computing the next occurrence of
+// every kind of recurrence rule
+func TestRecurrenceNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2026, 1, 28, 15, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		rule     string
+		expected string
+	}{
+		{"daily", "2026-01-29"},
+		{"weekly", "2026-02-04"},
+		{"weekly:mon,thu", "2026-01-29"},
+		{"weekly:Monday", "2026-02-02"},
+		{"weekly:wed", "2026-02-04"},
+		{"monthly:30", "2026-01-30"},
+		{"monthly:15", "2026-02-15"},
+		{"monthly:31", "2026-01-31"},
+		{"every:10", "2026-02-07"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := r.Next(from).Format(todo.DateLayout); next != tc.expected {
+				t.Errorf("Expected %s, got %s instead", tc.expected, next)
+			}
+		})
+	}
+
+	// the 31st falls on the last day of shorter months
+	r, _ := todo.ParseRecurrence("monthly:31")
+	next := r.Next(time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local))
+	if next.Format(todo.DateLayout) != "2026-02-28" {
+		t.Errorf("Expected %s, got %s instead", "2026-02-28", next.Format(todo.DateLayout))
+	}
+
+	for _, rule := range []string{"hourly", "daily:2", "weekly:xyz", "monthly:32", "every:0"} {
+		if _, err := todo.ParseRecurrence(rule); err == nil {
+			t.Errorf("Expected error for rule %q, got nil", rule)
+		}
+	}
+}
+
+// TestCompleteRecurring tests that completing a recurring item
+// adds its next occurrence
+func TestCompleteRecurring(t *testing.T) {
+	l := todo.List{}
+	l.Add("rotate logs +ops")
+
+	r, err := todo.ParseRecurrence("every:3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetRecurrence(1, r); err != nil {
+		t.Fatal(err)
+	}
+	due := time.Now().AddDate(0, 0, 1)
+	if err := l.SetDue(1, due); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	// reopening and completing it again doesn't add another one
+	if err := l.Uncomplete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 2 {
+		t.Fatalf("Expected %d items, got %d instead", 2, len(l))
+	}
+	if l[1].ID != 2 || l[1].Done || l[1].Task != l[0].Task || l[1].Recur == nil {
+		t.Errorf("Unexpected next occurrence %+v", l[1])
+	}
+
+	expected := due.AddDate(0, 0, 3).Format(todo.DateLayout)
+	if next := l[1].Due.Format(todo.DateLayout); next != expected {
+		t.Errorf("Expected next due date %s, got %s instead", expected, next)
+	}
+}
@@ -0,0 +1,129 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// SnoozeLayout is the layout used to print the time reminders are
+// snoozed until
+const SnoozeLayout = "2006-01-02 15:04"
+
+// Reminder is the notification of a task coming due
+type Reminder struct {
+	ID   int
+	Task string
+	Due  time.Time
+}
+
+// String prints the reminder as a one-line message
+func (r Reminder) String() string {
+	return fmt.Sprintf("Task %d is due %s: %s", r.ID, r.Due.Format(DateLayout), r.Task)
+}
+
+// Notifier delivers reminders
+type Notifier interface {
+	Notify(r Reminder) error
+}
+
+// Clock tells the current time, so tests can control it
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the Clock of the system
+var SystemClock Clock = systemClock{}
+
+// Scheduler decides when the reminders of a list fire. A pending
+// task with a due date fires once, at time At of its due day, or
+// when it's snoozed, once the snooze ends. Changing the due date
+// or snoozing the task again schedules a new reminder. What fired
+// is only remembered by the scheduler, so overdue tasks fire again
+// when a new scheduler first checks the list
+type Scheduler struct {
+	clock Clock
+	at    time.Duration
+	// the time each item fired for
+	fired map[int]time.Time
+}
+
+// NewScheduler returns a scheduler firing the reminders at the time
+// of day at, using clock to tell the time
+func NewScheduler(clock Clock, at time.Duration) *Scheduler {
+	return &Scheduler{clock: clock, at: at, fired: map[int]time.Time{}}
+}
+
+// Due returns the reminders of l due now which haven't fired yet,
+// and marks them as fired
+func (s *Scheduler) Due(l *List) []Reminder {
+	now := s.clock.Now()
+
+	reminders := []Reminder{}
+	for _, k := range l.view(nil, ByIndex) {
+		t := (*l)[k]
+		at := s.fireAt(t)
+		if at.IsZero() || at.After(now) || s.fired[t.ID].Equal(at) {
+			continue
+		}
+		s.fired[t.ID] = at
+		reminders = append(reminders, Reminder{ID: t.ID, Task: t.Task, Due: t.Due})
+	}
+
+	return reminders
+}
+
+// Next returns when the next reminder of l fires, or the zero time
+// if none is scheduled
+func (s *Scheduler) Next(l *List) time.Time {
+	var next time.Time
+	for _, k := range l.view(nil, ByIndex) {
+		t := (*l)[k]
+		at := s.fireAt(t)
+		if at.IsZero() || s.fired[t.ID].Equal(at) {
+			continue
+		}
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+
+	return next
+}
+
+// fireAt returns when the reminder of t fires, or the zero time if
+// it has none
+func (s *Scheduler) fireAt(t item) time.Time {
+	switch {
+	case t.Done || t.Due.IsZero():
+		return time.Time{}
+	case t.SnoozedUntil != nil:
+		return *t.SnoozedUntil
+	}
+	// the time of day is kept on days when clocks change
+	y, m, d := t.Due.Date()
+	h, min := int(s.at/time.Hour), int(s.at%time.Hour/time.Minute)
+	return time.Date(y, m, d, h, min, 0, 0, t.Due.Location())
+}
+
+// Snooze postpones the reminder of the item id until the given time
+func (l *List) Snooze(id int, until time.Time) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	t := (*l)[k]
+	switch {
+	case t.Done:
+		return fmt.Errorf("Item %d is completed", id)
+	case t.Due.IsZero():
+		return fmt.Errorf("Item %d has no due date", id)
+	}
+
+	(*l)[k].SnoozedUntil = &until
+	return nil
+}
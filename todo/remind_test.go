@@ -0,0 +1,120 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock tests move by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// TestScheduler tests when reminders fire, and how changing the
+// due date and snoozing schedule them again
+func TestScheduler(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+	clock := &fakeClock{now: day.Add(8 * time.Hour)}
+	s := todo.NewScheduler(clock, 9*time.Hour)
+
+	l := todo.List{}
+	l.Add("today\ntomorrow\nno due\ndone")
+	for id, due := range map[int]time.Time{1: day, 2: day.AddDate(0, 0, 1), 4: day} {
+		if err := l.SetDue(id, due); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Complete(4); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func() []int {
+		ids := []int{}
+		for _, r := range s.Due(&l) {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	check := func(expected ...int) {
+		t.Helper()
+		got := ids()
+		if len(got) != len(expected) {
+			t.Fatalf("Expected reminders %v at %s, got %v instead", expected, clock.now, got)
+		}
+		for k := range got {
+			if got[k] != expected[k] {
+				t.Fatalf("Expected reminders %v at %s, got %v instead", expected, clock.now, got)
+			}
+		}
+	}
+
+	check()
+	if next := s.Next(&l); !next.Equal(day.Add(9 * time.Hour)) {
+		t.Errorf("Expected the next reminder at 9:00, got %s", next)
+	}
+
+	clock.now = day.Add(9 * time.Hour)
+	check(1)
+	// reminders fire once
+	check()
+
+	// snoozing schedules the reminder again
+	until := clock.now.Add(time.Hour)
+	if err := l.Snooze(1, until); err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(&l); !next.Equal(until) {
+		t.Errorf("Expected the next reminder at %s, got %s", until, next)
+	}
+	clock.now = until.Add(-time.Minute)
+	check()
+	clock.now = until
+	check(1)
+
+	// so does moving the due date
+	if err := l.SetDue(1, day.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = day.AddDate(0, 0, 1).Add(10 * time.Hour)
+	check(1, 2)
+
+	if next := s.Next(&l); !next.IsZero() {
+		t.Errorf("Expected no reminder left, got %s", next)
+	}
+}
+
+// TestSchedulerDST tests that reminders fire at the time of day
+// set on days when clocks change
+func TestSchedulerDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("Time zone data not available:", err)
+	}
+	day := time.Date(2024, 3, 31, 0, 0, 0, 0, loc)
+	s := todo.NewScheduler(&fakeClock{now: day}, 9*time.Hour)
+
+	l := todo.List{}
+	l.Add("clocks go forward")
+	if err := l.SetDue(1, day); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2024, 3, 31, 9, 0, 0, 0, loc)
+	if next := s.Next(&l); !next.Equal(expected) {
+		t.Errorf("Expected the next reminder at %s, got %s instead", expected, next)
+	}
+}
+
+func TestSnooze(t *testing.T) {
+	l := todo.List{}
+	l.Add("no due")
+	if err := l.Snooze(1, time.Now()); err == nil {
+		t.Error("Expected an error snoozing a task without due date")
+	}
+	if err := l.Snooze(2, time.Now()); err == nil {
+		t.Error("Expected an error snoozing a missing task")
+	}
+}
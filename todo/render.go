@@ -0,0 +1,164 @@
+package todo
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Layouts of Render, any other layout is a template
+const (
+	LayoutText     = "text"
+	LayoutVerbose  = "verbose"
+	LayoutJSON     = "json"
+	LayoutCSV      = "csv"
+	LayoutMarkdown = "markdown"
+)
+
+// Layout is how Render prints the items, the zero Layout is the
+// text layout
+type Layout struct {
+	name string
+	tmpl *template.Template
+}
+
+// templateFuncs are the functions templates can use besides the
+// predefined ones
+var templateFuncs = template.FuncMap{
+	// date formats a time as YYYY-MM-DD, a zero time as ""
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(DateLayout)
+	},
+	"join": strings.Join,
+}
+
+// ParseLayout returns the layout called name: text, verbose, json,
+// csv or markdown. Any other name is parsed as a Go text/template
+// executed for every item, e.g. "{{.ID}}\t{{.Task}}\t{{date .Due}}".
+// Every item is followed by a newline
+func ParseLayout(name string) (Layout, error) {
+	switch name {
+	case "", LayoutText:
+		return Layout{}, nil
+	case LayoutVerbose, LayoutJSON, LayoutCSV, LayoutMarkdown:
+		return Layout{name: name}, nil
+	}
+
+	tmpl, err := template.New("item").Funcs(templateFuncs).Parse(name)
+	if err != nil {
+		return Layout{}, fmt.Errorf("Invalid layout %q: %w", name, err)
+	}
+	return Layout{name: "template", tmpl: tmpl}, nil
+}
+
+// Render writes the items accepted by the filter in the given
+// order using the layout. Subtasks follow their parent
+func (l *List) Render(w io.Writer, layout Layout, f Filter, o Order) error {
+	order, depth := l.tree(l.view(f, o))
+
+	switch layout.name {
+	case LayoutVerbose:
+		return l.renderVerbose(w, order)
+	case LayoutJSON:
+		items := List{}
+		for _, k := range order {
+			items = append(items, (*l)[k])
+		}
+		js, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(js))
+		return err
+	case LayoutCSV:
+		return l.renderCSV(w, order)
+	case LayoutMarkdown:
+		for c, k := range order {
+			t := (*l)[k]
+			box := "[ ]"
+			if t.Done {
+				box = "[x]"
+			}
+			indent := strings.Repeat("  ", depth[c])
+			if _, err := fmt.Fprintf(w, "%s- %s %s%s\n", indent, box, t.title(), l.blockedSuffix(t)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "template":
+		for _, k := range order {
+			if err := layout.tmpl.Execute(w, (*l)[k]); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, lines := l.Lines(f, o)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderVerbose writes the details of the items at the indexes
+func (l *List) renderVerbose(w io.Writer, order []int) error {
+	for _, k := range order {
+		t := (*l)[k]
+		prefix := "  "
+		if t.Done {
+			prefix = "X "
+		}
+		output := fmt.Sprintf("%sTask #%d detail:\n", prefix, t.ID)
+		output += fmt.Sprintf("\tName: %s\n\tCreated At: %s\n", t.Task, t.CreatedAt)
+		output += t.details()
+		if t.Done {
+			output += fmt.Sprintf("\tCompleted At: %s\n", t.CompletedAt)
+		}
+		if _, err := fmt.Fprintln(w, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderCSV writes the items at the indexes as CSV with a header
+// row. Times are written as RFC 3339 and due dates as YYYY-MM-DD
+func (l *List) renderCSV(w io.Writer, order []int) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "done", "priority", "task", "due", "created", "completed", "tags", "parent"})
+
+	for _, k := range order {
+		t := (*l)[k]
+		due, completed, parent := "", "", ""
+		if !t.Due.IsZero() {
+			due = t.Due.Format(DateLayout)
+		}
+		if t.Done {
+			completed = t.CompletedAt.Format(time.RFC3339)
+		}
+		if t.Parent != 0 {
+			parent = strconv.Itoa(t.Parent)
+		}
+		cw.Write([]string{
+			strconv.Itoa(t.ID), strconv.FormatBool(t.Done), t.Priority, t.Task, due,
+			t.CreatedAt.Format(time.RFC3339), completed, strings.Join(t.tags(), " "), parent,
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
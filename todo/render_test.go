@@ -0,0 +1,74 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+// render renders the list with the named layout
+func render(t *testing.T, l *todo.List, layout string, f todo.Filter, o todo.Order) string {
+	t.Helper()
+
+	lo, err := todo.ParseLayout(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := l.Render(&sb, lo, f, o); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+// TestRender tests the layouts of Render
+func TestRender(t *testing.T) {
+	l := todo.List{}
+	l.Add("write report +work\nadd charts\nbuy milk, bread")
+	if err := l.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDue(1, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		layout   string
+		expected string
+	}{
+		{"text", "  1: write report +work (due 2026-03-01)\n    2: add charts\nX 3: buy milk, bread\n"},
+		{"markdown", "- [ ] write report +work (due 2026-03-01)\n  - [ ] add charts\n- [x] buy milk, bread\n"},
+		{"{{.ID}}|{{.Done}}|{{date .Due}}", "1|false|2026-03-01\n2|false|\n3|true|\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.layout, func(t *testing.T) {
+			if out := render(t, &l, tc.layout, nil, todo.ByIndex); out != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, out)
+			}
+		})
+	}
+
+	out := render(t, &l, "csv", todo.Pending, todo.ByIndex)
+	lines := strings.Split(out, "\n")
+	if len(lines) != 4 || lines[0] != "id,done,priority,task,due,created,completed,tags,parent" {
+		t.Fatalf("Unexpected CSV output %q", out)
+	}
+	if !strings.HasPrefix(lines[1], "1,false,,write report +work,2026-03-01,") || !strings.HasSuffix(lines[1], ",,+work,") {
+		t.Errorf("Unexpected CSV row %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], ",1") {
+		t.Errorf("Expected the parent in the CSV row, got %q", lines[2])
+	}
+
+	if l.String() != render(t, &l, "text", nil, todo.ByIndex) {
+		t.Errorf("Expected String to use the text layout")
+	}
+
+	if _, err := todo.ParseLayout("{{.ID"); err == nil {
+		t.Errorf("Expected error for an invalid template")
+	}
+}
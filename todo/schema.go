@@ -0,0 +1,168 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON files written by Save.
+// Files are kept in an envelope holding the version, metadata and
+// the items. Older layouts are migrated forward when read:
+//
+//	0  a bare array of items, saved before items had IDs and tags
+//	1  a bare array of items with IDs
+//	2  the envelope
+//
+// Changing the layout of the items in a way older files can't be
+// decoded into needs a new version and a migration step
+const SchemaVersion = 2
+
+// Meta is the metadata kept in the envelope of JSON files
+type Meta struct {
+	// Saved is when the file was last saved
+	Saved time.Time
+}
+
+// envelope is the layout of JSON files since version 2
+type envelope struct {
+	Version int
+	Meta    Meta
+	Items   List
+}
+
+// migrations[v] migrates a file from version v to version v+1. They
+// work on the JSON data rather than on items, so they keep working
+// when the item struct changes
+var migrations = []func(data []byte) ([]byte, error){
+	migrateIDs,
+	migrateEnvelope,
+}
+
+// Migrate migrates the JSON data of a ToDo file from the version it
+// was written with to the given version, up to SchemaVersion
+func Migrate(data []byte, version int) ([]byte, error) {
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("Invalid version %d, the latest is %d", version, SchemaVersion)
+	}
+
+	v, err := fileVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if v > version {
+		return nil, fmt.Errorf("Can't migrate version %d back to %d", v, version)
+	}
+
+	for ; v < version; v++ {
+		if data, err = migrations[v](data); err != nil {
+			return nil, fmt.Errorf("Can't migrate from version %d: %w", v, err)
+		}
+	}
+	return data, nil
+}
+
+// fileVersion returns the version of the JSON data of a ToDo file
+func fileVersion(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		items := []map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return 0, err
+		}
+		for _, t := range items {
+			if _, ok := t["ID"]; !ok {
+				return 0, nil
+			}
+		}
+		return 1, nil
+	case bytes.HasPrefix(data, []byte("{")):
+		e := struct{ Version int }{}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return 0, err
+		}
+		if e.Version < 2 {
+			return 0, errors.New("Invalid file, the version is missing")
+		}
+		if e.Version > SchemaVersion {
+			return 0, fmt.Errorf("The file was written with version %d of the format, newer than the version %d this program reads, please upgrade", e.Version, SchemaVersion)
+		}
+		return e.Version, nil
+	}
+
+	return 0, errors.New("Invalid file, expected a JSON array or object")
+}
+
+// migrateIDs gives IDs to the items saved before items had one,
+// and stores the tags which were only kept in their text. Items
+// keep the number they were shown with, as long as the list has
+// no IDs at all
+func migrateIDs(data []byte) ([]byte, error) {
+	items := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	next := 1
+	for _, t := range items {
+		id := 0
+		if raw, ok := t["ID"]; ok {
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return nil, err
+			}
+		}
+		if id >= next {
+			next = id + 1
+		}
+	}
+
+	for _, t := range items {
+		if _, ok := t["ID"]; !ok {
+			t["ID"] = json.RawMessage(fmt.Sprint(next))
+			next++
+		}
+
+		_, hasProjects := t["Projects"]
+		_, hasContexts := t["Contexts"]
+		if hasProjects || hasContexts {
+			continue
+		}
+		task := ""
+		if raw, ok := t["Task"]; ok {
+			if err := json.Unmarshal(raw, &task); err != nil {
+				return nil, err
+			}
+		}
+		projects, contexts := parseTags(task)
+		for key, tags := range map[string][]string{"Projects": projects, "Contexts": contexts} {
+			if len(tags) == 0 {
+				continue
+			}
+			raw, err := json.Marshal(tags)
+			if err != nil {
+				return nil, err
+			}
+			t[key] = raw
+		}
+	}
+
+	return json.Marshal(items)
+}
+
+// migrateEnvelope wraps the bare array of items in the envelope
+func migrateEnvelope(data []byte) ([]byte, error) {
+	items := []json.RawMessage{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Version int
+		Meta    Meta
+		Items   []json.RawMessage
+	}{Version: 2, Items: items})
+}
@@ -0,0 +1,113 @@
+package todo_test
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the migrations")
+
+// golden returns the golden file of a schema version
+func golden(version int) string {
+	return filepath.Join("testdata", "schema", fmt.Sprintf("v%d.json", version))
+}
+
+// TestMigrate tests every migration step against golden files: the
+// file of each version migrated one step must match the file of the
+// next version
+func TestMigrate(t *testing.T) {
+	for v := 0; v < todo.SchemaVersion; v++ {
+		t.Run(fmt.Sprintf("v%dToV%d", v, v+1), func(t *testing.T) {
+			data, err := os.ReadFile(golden(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := todo.Migrate(data, v+1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *update {
+				var buf bytes.Buffer
+				if err := json.Indent(&buf, got, "", "  "); err != nil {
+					t.Fatal(err)
+				}
+				buf.WriteString("\n")
+				if err := os.WriteFile(golden(v+1), buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden(v + 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var a, b interface{}
+			if err := json.Unmarshal(got, &a); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(expected, &b); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a, b) {
+				t.Errorf("Expected %s, got %s instead", expected, got)
+			}
+		})
+	}
+}
+
+// TestGetVersions tests that the files of every version are read
+// as the same list, and saved with the latest version
+func TestGetVersions(t *testing.T) {
+	var first string
+	for v := 0; v <= todo.SchemaVersion; v++ {
+		l := todo.List{}
+		if err := l.Get(golden(v)); err != nil {
+			t.Fatalf("Reading version %d: %v", v, err)
+		}
+		out := l.String()
+		for _, k := range l {
+			out += fmt.Sprintf("%d %v %v\n", k.ID, k.Projects, k.Contexts)
+		}
+		if v == 0 {
+			first = out
+		} else if out != first {
+			t.Errorf("Expected version %d to read as %q, got %q instead", v, first, out)
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	l := todo.List{}
+	l.Add("task")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), fmt.Sprintf(`{"Version":%d,`, todo.SchemaVersion)) {
+		t.Errorf("Expected the file to be saved with version %d, got %s", todo.SchemaVersion, data)
+	}
+}
+
+// TestNewerVersion tests that files of an unknown version aren't read
+func TestNewerVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	data := fmt.Sprintf(`{"Version":%d,"Items":[]}`, todo.SchemaVersion+1)
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&todo.List{}).Get(filename); err == nil || !strings.Contains(err.Error(), "please upgrade") {
+		t.Errorf("Expected an error asking to upgrade, got %v", err)
+	}
+}
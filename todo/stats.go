@@ -0,0 +1,180 @@
+package todo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// oldestCount is the number of oldest open tasks in Stats
+const oldestCount = 5
+
+// burndownWidth is the width of the longest bar of the burndown chart
+const burndownWidth = 40
+
+// Stats holds the statistics of a list over a range of days
+type Stats struct {
+	From      time.Time
+	To        time.Time
+	By        string
+	Created   int
+	Completed int
+	// AverageLeadDays is the average time, in days, from creating
+	// to completing the items completed in the range
+	AverageLeadDays float64
+	Periods         []Period
+	Oldest          []OpenItem
+	Burndown        []Burndown
+}
+
+// Period counts the items created and completed in the day or
+// week starting at Start
+type Period struct {
+	Start     time.Time
+	Created   int
+	Completed int
+}
+
+// OpenItem is a pending item shown in the statistics
+type OpenItem struct {
+	ID        int
+	Task      string
+	CreatedAt time.Time
+}
+
+// Burndown is the number of open items at the end of a day
+type Burndown struct {
+	Day  time.Time
+	Open int
+}
+
+// Stats computes the statistics of the items accepted by the
+// filter between the days from and to, included, with the
+// created and completed items counted per "day" or "week"
+func (l *List) Stats(f Filter, from, to time.Time, by string) (Stats, error) {
+	if by != "day" && by != "week" {
+		return Stats{}, fmt.Errorf("Invalid period %q, expected day or week", by)
+	}
+	from, to = startOfDay(from), startOfDay(to)
+	if to.Before(from) {
+		return Stats{}, fmt.Errorf("Invalid range, %s is after %s", from.Format(DateLayout), to.Format(DateLayout))
+	}
+	end := to.AddDate(0, 0, 1)
+
+	s := Stats{From: from, To: to, By: by}
+	items := []item{}
+	for _, k := range l.view(f, ByIndex) {
+		items = append(items, (*l)[k])
+	}
+
+	// the periods cover the range, weeks start on Monday
+	start := from
+	if by == "week" {
+		start = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	}
+	index := map[string]int{}
+	for d := start; d.Before(end); {
+		index[d.Format(DateLayout)] = len(s.Periods)
+		s.Periods = append(s.Periods, Period{Start: d})
+		if by == "week" {
+			d = d.AddDate(0, 0, 7)
+		} else {
+			d = d.AddDate(0, 0, 1)
+		}
+	}
+	period := func(t time.Time) *Period {
+		d := startOfDay(t.In(time.Local))
+		if by == "week" {
+			d = d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+		}
+		return &s.Periods[index[d.Format(DateLayout)]]
+	}
+	inRange := func(t time.Time) bool {
+		return !t.IsZero() && !t.Before(from) && t.Before(end)
+	}
+
+	var lead time.Duration
+	for _, t := range items {
+		if inRange(t.CreatedAt) {
+			s.Created++
+			period(t.CreatedAt).Created++
+		}
+		if t.Done && inRange(t.CompletedAt) {
+			s.Completed++
+			period(t.CompletedAt).Completed++
+			lead += t.CompletedAt.Sub(t.CreatedAt)
+		}
+	}
+	if s.Completed > 0 {
+		days := lead.Hours() / 24 / float64(s.Completed)
+		s.AverageLeadDays = math.Round(days*100) / 100
+	}
+
+	// items open at the end of each day of the range
+	for d := from; d.Before(end); d = d.AddDate(0, 0, 1) {
+		next := d.AddDate(0, 0, 1)
+		b := Burndown{Day: d}
+		for _, t := range items {
+			if t.CreatedAt.Before(next) && (!t.Done || !t.CompletedAt.Before(next)) {
+				b.Open++
+			}
+		}
+		s.Burndown = append(s.Burndown, b)
+	}
+
+	open := []item{}
+	for _, t := range items {
+		if !t.Done {
+			open = append(open, t)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].CreatedAt.Before(open[j].CreatedAt)
+	})
+	for k := 0; k < len(open) && k < oldestCount; k++ {
+		t := open[k]
+		s.Oldest = append(s.Oldest, OpenItem{ID: t.ID, Task: t.Task, CreatedAt: t.CreatedAt})
+	}
+
+	return s, nil
+}
+
+// String prints the statistics as a text report with a burndown chart
+func (s Stats) String() string {
+	output := fmt.Sprintf("Report from %s to %s\n\n", s.From.Format(DateLayout), s.To.Format(DateLayout))
+	output += fmt.Sprintf("Created: %d\nCompleted: %d\n", s.Created, s.Completed)
+	if s.Completed > 0 {
+		output += fmt.Sprintf("Average lead time: %.2f days\n", s.AverageLeadDays)
+	}
+
+	output += fmt.Sprintf("\nPer %s:\n", s.By)
+	for _, p := range s.Periods {
+		output += fmt.Sprintf("  %s: %d created, %d completed\n", p.Start.Format(DateLayout), p.Created, p.Completed)
+	}
+
+	if len(s.Oldest) > 0 {
+		output += "\nOldest open tasks:\n"
+		for _, t := range s.Oldest {
+			output += fmt.Sprintf("  %d: %s (created %s)\n", t.ID, t.Task, t.CreatedAt.Format(DateLayout))
+		}
+	}
+
+	max := 0
+	for _, b := range s.Burndown {
+		if b.Open > max {
+			max = b.Open
+		}
+	}
+	output += "\nOpen tasks:\n"
+	for _, b := range s.Burndown {
+		bar := b.Open
+		if max > burndownWidth {
+			bar = int(math.Round(float64(b.Open) * burndownWidth / float64(max)))
+		}
+		output += fmt.Sprintf("  %s |%s %d\n", b.Day.Format(DateLayout), strings.Repeat("#", bar), b.Open)
+	}
+
+	return output
+}
@@ -0,0 +1,89 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStats tests the statistics computed over a range of days
+func TestStats(t *testing.T) {
+	day := func(d, h int) time.Time {
+		return time.Date(2026, 10, d, h, 0, 0, 0, time.Local)
+	}
+
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3\ntask 4")
+	l[0].CreatedAt = day(1, 9)
+	l[1].CreatedAt, l[1].Done, l[1].CompletedAt = day(5, 9), true, day(6, 9)
+	l[2].CreatedAt, l[2].Done, l[2].CompletedAt = day(5, 9), true, day(8, 9)
+	l[3].CreatedAt = day(7, 9)
+
+	s, err := l.Stats(nil, day(5, 0), day(8, 0), "day")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Created != 3 || s.Completed != 2 {
+		t.Errorf("Expected 3 created and 2 completed, got %d and %d", s.Created, s.Completed)
+	}
+	if s.AverageLeadDays != 2 {
+		t.Errorf("Expected an average lead time of 2 days, got %v", s.AverageLeadDays)
+	}
+	if len(s.Periods) != 4 || s.Periods[0].Created != 2 || s.Periods[3].Completed != 1 {
+		t.Errorf("Unexpected periods %+v", s.Periods)
+	}
+
+	open := []int{}
+	for _, b := range s.Burndown {
+		open = append(open, b.Open)
+	}
+	expected := []int{3, 2, 3, 2}
+	for k := range expected {
+		if open[k] != expected[k] {
+			t.Errorf("Expected burndown %v, got %v instead", expected, open)
+			break
+		}
+	}
+
+	if len(s.Oldest) != 2 || s.Oldest[0].ID != 1 || s.Oldest[1].ID != 4 {
+		t.Errorf("Expected items 1 and 4 as oldest open tasks, got %+v", s.Oldest)
+	}
+
+	out := s.String()
+	for _, e := range []string{
+		"Report from 2026-10-05 to 2026-10-08\n",
+		"Average lead time: 2.00 days\n",
+		"  2026-10-05: 2 created, 0 completed\n",
+		"  1: task 1 (created 2026-10-01)\n",
+		"  2026-10-07 |### 3\n",
+	} {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected output to contain %q, got %q", e, out)
+		}
+	}
+}
+
+// TestStatsWeek tests counting items per week
+func TestStatsWeek(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2")
+	l[0].CreatedAt = time.Date(2026, 10, 11, 9, 0, 0, 0, time.Local)
+	l[1].CreatedAt = time.Date(2026, 10, 12, 9, 0, 0, 0, time.Local)
+
+	s, err := l.Stats(nil, l[0].CreatedAt, l[1].CreatedAt, "week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Periods) != 2 || s.Periods[0].Start.Day() != 5 || s.Periods[0].Created != 1 || s.Periods[1].Created != 1 {
+		t.Errorf("Unexpected periods %+v", s.Periods)
+	}
+
+	if _, err := l.Stats(nil, l[1].CreatedAt, l[0].CreatedAt, "day"); err == nil {
+		t.Errorf("Expected error for an invalid range")
+	}
+	if _, err := l.Stats(nil, l[0].CreatedAt, l[1].CreatedAt, "month"); err == nil {
+		t.Errorf("Expected error for an invalid period")
+	}
+}
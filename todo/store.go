@@ -0,0 +1,271 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Storage formats supported by NewStore
+const (
+	FormatJSON = "json"
+	FormatTxt  = "txt"
+	FormatDB   = "db"
+)
+
+// Store loads and saves a List from a storage backend
+type Store interface {
+	// Load reads the stored items into l. A store which
+	// doesn't exist yet loads an empty list
+	Load(l *List) error
+	// Save replaces the stored items with the items of l
+	Save(l *List) error
+}
+
+// NewStore returns the Store keeping the list in filename using the
+// given format. An empty format is picked from the file extension:
+// .txt for todo.txt files, .db for a key/value database and JSON
+// for anything else
+func NewStore(filename, format string) (Store, error) {
+	return NewStoreWithKey(filename, format, nil)
+}
+
+// NewStoreWithKey returns the Store of NewStore, encrypting the
+// file with key when it isn't nil. The db format can't be encrypted
+func NewStoreWithKey(filename, format string, key *Key) (Store, error) {
+	if format == "" {
+		switch filepath.Ext(filename) {
+		case ".txt":
+			format = FormatTxt
+		case ".db":
+			format = FormatDB
+		default:
+			format = FormatJSON
+		}
+	}
+
+	switch format {
+	case FormatJSON:
+		return &jsonStore{filename: filename, key: key}, nil
+	case FormatTxt:
+		return &txtStore{filename: filename, key: key}, nil
+	case FormatDB:
+		if key != nil {
+			return nil, errors.New("The db format doesn't support encryption, use json or txt")
+		}
+		return NewDBStore(filename), nil
+	}
+
+	return nil, fmt.Errorf("Invalid storage format %q, expected json, txt or db", format)
+}
+
+// jsonStore keeps the list as a JSON array, see List.GetWithKey and
+// List.SaveWithKey
+type jsonStore struct {
+	filename string
+	key      *Key
+}
+
+// NewJSONStore returns a Store keeping the list in a JSON file
+func NewJSONStore(filename string) Store {
+	return &jsonStore{filename: filename}
+}
+
+func (s *jsonStore) Load(l *List) error {
+	return l.GetWithKey(s.filename, s.key)
+}
+
+func (s *jsonStore) Save(l *List) error {
+	return l.SaveWithKey(s.filename, s.key)
+}
+
+// txtStore keeps the list as a todo.txt file, one task per line
+type txtStore struct {
+	filename string
+	key      *Key
+}
+
+// NewTxtStore returns a Store keeping the list in a todo.txt file.
+// Fields todo.txt has no syntax for are kept as key:value extensions
+func NewTxtStore(filename string) Store {
+	return &txtStore{filename: filename}
+}
+
+func (s *txtStore) Load(l *List) error {
+	data, err := readFile(s.filename, s.key)
+	if err != nil {
+		return err
+	}
+
+	return l.ReadTxt(bytes.NewReader(data))
+}
+
+func (s *txtStore) Save(l *List) error {
+	var buf bytes.Buffer
+	if err := l.WriteTxt(&buf); err != nil {
+		return err
+	}
+
+	return writeSealed(s.filename, buf.Bytes(), s.key)
+}
+
+var (
+	itemsBucket = []byte("items")
+	metaBucket  = []byte("meta")
+	orderKey    = []byte("order")
+)
+
+// dbStore keeps the list in a single-file bolt database. Every
+// item is a separate JSON value keyed by its ID, so saving a large
+// list only rewrites the items which changed
+type dbStore struct {
+	filename string
+}
+
+// NewDBStore returns a Store keeping the list in an embedded
+// key/value database file
+func NewDBStore(filename string) Store {
+	return &dbStore{filename: filename}
+}
+
+func (s *dbStore) open() (*bolt.DB, error) {
+	return bolt.Open(s.filename, 0644, &bolt.Options{Timeout: lockTimeout})
+}
+
+func (s *dbStore) Load(l *List) error {
+	if _, err := os.Stat(s.filename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = db.View(func(tx *bolt.Tx) error {
+		items := tx.Bucket(itemsBucket)
+		if items == nil {
+			return nil
+		}
+
+		// items are read in the order they were saved
+		order := []int{}
+		if meta := tx.Bucket(metaBucket); meta != nil {
+			if v := meta.Get(orderKey); v != nil {
+				if err := json.Unmarshal(v, &order); err != nil {
+					return err
+				}
+			}
+		}
+
+		seen := map[int]bool{}
+		for _, id := range order {
+			v := items.Get(dbKey(id))
+			if v == nil {
+				continue
+			}
+			t := item{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("Invalid item %d: %w", id, err)
+			}
+			*l = append(*l, t)
+			seen[id] = true
+		}
+
+		// items missing from the order come last
+		return items.ForEach(func(k, v []byte) error {
+			if seen[int(binary.BigEndian.Uint64(k))] {
+				return nil
+			}
+			t := item{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			*l = append(*l, t)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	l.normalize()
+
+	return nil
+}
+
+func (s *dbStore) Save(l *List) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		items, err := tx.CreateBucketIfNotExists(itemsBucket)
+		if err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		order := make([]int, 0, len(*l))
+		keep := map[string]bool{}
+		for _, t := range *l {
+			v, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+
+			k := dbKey(t.ID)
+			keep[string(k)] = true
+			order = append(order, t.ID)
+
+			// unchanged items are left alone
+			if bytes.Equal(items.Get(k), v) {
+				continue
+			}
+			if err := items.Put(k, v); err != nil {
+				return err
+			}
+		}
+
+		// remove the items deleted from the list
+		stale := [][]byte{}
+		if err := items.ForEach(func(k, v []byte) error {
+			if !keep[string(k)] {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range stale {
+			if err := items.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		v, err := json.Marshal(order)
+		if err != nil {
+			return err
+		}
+		return meta.Put(orderKey, v)
+	})
+}
+
+// dbKey returns the database key of the item id. Keys are big
+// endian so the database keeps them sorted by ID
+func dbKey(id int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
@@ -0,0 +1,82 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStores tests that every storage backend saves and loads
+// the same list
+func TestStores(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filename string
+		format   string
+	}{
+		{"JSON", "todo.json", ""},
+		{"Txt", "todo.txt", ""},
+		{"DB", "todo.db", ""},
+		{"DBFlag", "todo.json", todo.FormatDB},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := todo.NewStore(filepath.Join(t.TempDir(), tc.filename), tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l1 := todo.List{}
+			if err := s.Load(&l1); err != nil {
+				t.Fatalf("Error loading missing store: %s", err)
+			}
+
+			l1.Add("New Task 1 +work\nNew Task 2 @home\nNew Task 3")
+			if err := l1.SetPriority(2, "B"); err != nil {
+				t.Fatal(err)
+			}
+			if err := l1.SetDue(3, time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)); err != nil {
+				t.Fatal(err)
+			}
+			if err := l1.Delete(1); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l1.Add("New Task 4\nNew Task 5")
+			if err := l1.Complete(3); err != nil {
+				t.Fatal(err)
+			}
+			if err := l1.Delete(5); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Save(&l1); err != nil {
+				t.Fatal(err)
+			}
+
+			l2 := todo.List{}
+			if err := s.Load(&l2); err != nil {
+				t.Fatal(err)
+			}
+
+			if l1.String() != l2.String() {
+				t.Errorf("Expected %q, got %q instead", l1.String(), l2.String())
+			}
+
+			// the ID of the deleted item isn't given again
+			l1.Add("New Task 6")
+			l2.Add("New Task 6")
+			if l1.String() != l2.String() {
+				t.Errorf("Expected %q, got %q instead", l1.String(), l2.String())
+			}
+		})
+	}
+
+	if _, err := todo.NewStore("todo.json", "xml"); err == nil {
+		t.Errorf("Expected error for invalid format, got nil")
+	}
+}
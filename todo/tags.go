@@ -0,0 +1,146 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseTags extracts the +project and @context tags from the
+// words of a task. Repeated tags are only returned once
+func parseTags(task string) (projects, contexts []string) {
+	for _, w := range strings.Fields(task) {
+		if len(w) < 2 {
+			continue
+		}
+
+		switch w[0] {
+		case '+':
+			projects = appendTag(projects, w[1:])
+		case '@':
+			contexts = appendTag(contexts, w[1:])
+		}
+	}
+
+	return projects, contexts
+}
+
+// appendTag appends tag to tags if it isn't there yet
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// hasTag reports whether the item has the given tag, written
+// with its + or @ prefix
+func (t item) hasTag(tag string) bool {
+	var tags []string
+	switch tag[0] {
+	case '+':
+		tags = t.Projects
+	case '@':
+		tags = t.Contexts
+	}
+
+	for _, v := range tags {
+		if v == tag[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFilter parses a tag expression into a Filter. Tags separated
+// by spaces must all be present (AND), groups separated by commas
+// are alternatives (OR) and a tag prefixed by ! must be absent (NOT).
+// For example "+work !@phone, +home" keeps the items of project work
+// without context phone, and every item of project home
+func TagFilter(expr string) (Filter, error) {
+	groups := [][]string{}
+
+	for _, g := range strings.Split(expr, ",") {
+		terms := strings.Fields(g)
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("Invalid tag expression %q: empty group", expr)
+		}
+
+		for _, term := range terms {
+			tag := strings.TrimPrefix(term, "!")
+			if len(tag) < 2 || (tag[0] != '+' && tag[0] != '@') {
+				return nil, fmt.Errorf("Invalid tag %q, expected +project or @context", term)
+			}
+		}
+		groups = append(groups, terms)
+	}
+
+	return func(t item) bool {
+		for _, terms := range groups {
+			match := true
+			for _, term := range terms {
+				if strings.HasPrefix(term, "!") {
+					match = !t.hasTag(term[1:])
+				} else {
+					match = t.hasTag(term)
+				}
+				if !match {
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Tags prints every tag used in the list with the number of
+// tasks using it and how many of them are still pending
+func (l *List) Tags() string {
+	total := map[string]int{}
+	pending := map[string]int{}
+
+	for _, t := range *l {
+		if t.hidden() {
+			continue
+		}
+
+		for _, tag := range t.tags() {
+			total[tag]++
+			if !t.Done {
+				pending[tag]++
+			}
+		}
+	}
+
+	names := make([]string, 0, len(total))
+	for tag := range total {
+		names = append(names, tag)
+	}
+	// '+' sorts before '@', so projects are listed first
+	sort.Strings(names)
+
+	output := ""
+	for _, tag := range names {
+		output += fmt.Sprintf("%s: %d (%d pending)\n", tag, total[tag], pending[tag])
+	}
+
+	return output
+}
+
+// tags returns the projects and contexts of the item, with their
+// prefix
+func (t item) tags() []string {
+	tags := []string{}
+	for _, p := range t.Projects {
+		tags = append(tags, "+"+p)
+	}
+	for _, c := range t.Contexts {
+		tags = append(tags, "@"+c)
+	}
+	return tags
+}
@@ -0,0 +1,68 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"testing"
+)
+
+// TestAddTags tests that tags are parsed out of the task text
+func TestAddTags(t *testing.T) {
+	l := todo.List{}
+	l.Add("call bob +work @phone +work +")
+
+	if len(l[0].Projects) != 1 || l[0].Projects[0] != "work" {
+		t.Errorf("Expected projects [work], got %v instead", l[0].Projects)
+	}
+	if len(l[0].Contexts) != 1 || l[0].Contexts[0] != "phone" {
+		t.Errorf("Expected contexts [phone], got %v instead", l[0].Contexts)
+	}
+}
+
+// TestTagFilter tests filtering the list with tag expressions
+func TestTagFilter(t *testing.T) {
+	l := todo.List{}
+	l.Add("a +work @phone\nb +work\nc +home @phone\nd")
+
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"+work", "  1: a +work @phone\n  2: b +work\n"},
+		{"+work @phone", "  1: a +work @phone\n"},
+		{"+work !@phone", "  2: b +work\n"},
+		{"+work !@phone, +home", "  2: b +work\n  3: c +home @phone\n"},
+		{"!+work !+home", "  4: d\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			f, err := todo.TagFilter(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out := render(t, &l, "text", f, todo.ByIndex); out != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, out)
+			}
+		})
+	}
+
+	for _, expr := range []string{"work", "+work,", "!"} {
+		if _, err := todo.TagFilter(expr); err == nil {
+			t.Errorf("Expected error for expression %q, got nil", expr)
+		}
+	}
+}
+
+// TestTags tests the tag counts view
+func TestTags(t *testing.T) {
+	l := todo.List{}
+	l.Add("a +work @phone\nb +work\nc @phone")
+	if err := l.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "+work: 2 (1 pending)\n@phone: 2 (1 pending)\n"
+	if out := l.Tags(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+}
@@ -0,0 +1,196 @@
+package todo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interval is a period of work on an item. Stop is zero while
+// the work is still going on
+type Interval struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// TimeEntry is the time tracked in one day or week, or on one tag
+type TimeEntry struct {
+	Key  string
+	Time time.Duration
+}
+
+// noTag is the key of the time tracked on items without tags
+const noTag = "(no tag)"
+
+// Start starts tracking the time spent on the item id. Only one
+// item is worked on at a time, so the time tracked on any other
+// item is stopped first
+func (l *List) Start(id int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	t := (*l)[k]
+	switch {
+	case t.started():
+		return fmt.Errorf("Item %d is already started", id)
+	case t.Done:
+		return fmt.Errorf("Item %d is completed", id)
+	}
+
+	if l.Active() != 0 {
+		l.Stop()
+	}
+
+	intervals := append([]Interval{}, t.Intervals...)
+	(*l)[k].Intervals = append(intervals, Interval{Start: time.Now()})
+	return nil
+}
+
+// Stop stops tracking the time on the item being worked on
+func (l *List) Stop() error {
+	id := l.Active()
+	if id == 0 {
+		return errors.New("No item is started")
+	}
+
+	k, _ := l.index(id)
+	intervals := append([]Interval{}, (*l)[k].Intervals...)
+	intervals[len(intervals)-1].Stop = time.Now()
+	(*l)[k].Intervals = intervals
+	return nil
+}
+
+// Active returns the ID of the item being worked on, or 0
+func (l *List) Active() int {
+	for _, t := range *l {
+		if t.started() {
+			return t.ID
+		}
+	}
+	return 0
+}
+
+// TimeReport sums the time tracked on the items accepted by the
+// filter per "day", per "week" (starting on Monday, named after
+// its first day) or per "tag". Work still going on is counted up
+// to now. Days and weeks are sorted by date and tags by name
+func (l *List) TimeReport(f Filter, by string, now time.Time) ([]TimeEntry, error) {
+	if by != "day" && by != "week" && by != "tag" {
+		return nil, fmt.Errorf("Invalid report %q, expected day, week or tag", by)
+	}
+
+	total := map[string]time.Duration{}
+	for _, k := range l.view(f, ByIndex) {
+		t := (*l)[k]
+		if by == "tag" {
+			d := t.tracked(now)
+			if d == 0 {
+				continue
+			}
+			tags := t.tags()
+			if len(tags) == 0 {
+				tags = []string{noTag}
+			}
+			for _, tag := range tags {
+				total[tag] += d
+			}
+			continue
+		}
+
+		for _, i := range t.Intervals {
+			stop := i.Stop
+			if stop.IsZero() {
+				stop = now
+			}
+			// split the interval at midnight, so every day gets
+			// the time spent on it
+			for cur := i.Start; cur.Before(stop); {
+				day := startOfDay(cur.In(time.Local))
+				end := day.AddDate(0, 0, 1)
+				if stop.Before(end) {
+					end = stop
+				}
+				if by == "week" {
+					day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+				}
+				total[day.Format(DateLayout)] += end.Sub(cur)
+				cur = end
+			}
+		}
+	}
+
+	entries := make([]TimeEntry, 0, len(total))
+	for key, d := range total {
+		entries = append(entries, TimeEntry{Key: key, Time: d})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries, nil
+}
+
+// TimeString prints a time report, one entry per line followed
+// by the total
+func TimeString(entries []TimeEntry) string {
+	output := ""
+	var total time.Duration
+	for _, e := range entries {
+		output += fmt.Sprintf("%s: %s\n", e.Key, formatDuration(e.Time))
+		total += e.Time
+	}
+
+	return output + fmt.Sprintf("Total: %s\n", formatDuration(total))
+}
+
+// WriteTimeCSV writes a time report as CSV with a header row, the
+// time given in hours with two decimals
+func WriteTimeCSV(w io.Writer, by string, entries []TimeEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{by, "hours"})
+	for _, e := range entries {
+		cw.Write([]string{e.Key, fmt.Sprintf("%.2f", e.Time.Hours())})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// started reports whether the item is being worked on
+func (t item) started() bool {
+	n := len(t.Intervals)
+	return n > 0 && t.Intervals[n-1].Stop.IsZero()
+}
+
+// tracked returns the time spent on the item, counting work still
+// going on up to now
+func (t item) tracked(now time.Time) time.Duration {
+	var d time.Duration
+	for _, i := range t.Intervals {
+		stop := i.Stop
+		if stop.IsZero() {
+			stop = now
+		}
+		d += stop.Sub(i.Start)
+	}
+	return d
+}
+
+// formatDuration prints a duration rounded to the minute, e.g. 1h30m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d == 0 {
+		return "0m"
+	}
+	s := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
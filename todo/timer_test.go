@@ -0,0 +1,125 @@
+package todo_test
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStartStop tests tracking time with one item started at a time
+func TestStartStop(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2")
+
+	if err := l.Stop(); err == nil {
+		t.Errorf("Expected error with no started item")
+	}
+	if err := l.Start(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Start(1); err == nil {
+		t.Errorf("Expected error starting an item twice")
+	}
+
+	// starting another item stops the first one
+	if err := l.Start(2); err != nil {
+		t.Fatal(err)
+	}
+	if l.Active() != 2 {
+		t.Errorf("Expected item %d to be active, got %d instead", 2, l.Active())
+	}
+	if l[0].Intervals[0].Stop.IsZero() {
+		t.Errorf("Expected item 1 to be stopped")
+	}
+
+	if out := render(t, &l, "verbose", nil, todo.ByIndex); !strings.Contains(out, "\tTime Spent: 0m (running)\n") {
+		t.Errorf("Expected the time spent in the verbose output, got %q", out)
+	}
+
+	// completing an item stops it
+	if err := l.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+	if l.Active() != 0 {
+		t.Errorf("Expected no active item, got %d", l.Active())
+	}
+	if err := l.Start(2); err == nil {
+		t.Errorf("Expected error starting a completed item")
+	}
+}
+
+// TestTimeReport tests summing the tracked time per day, week and tag
+func TestTimeReport(t *testing.T) {
+	l := todo.List{}
+	l.Add("deploy +work\nread")
+
+	// Sunday 23:00 to Monday 01:30, and a running interval
+	sunday := time.Date(2026, 10, 11, 23, 0, 0, 0, time.Local)
+	l[0].Intervals = []todo.Interval{{Start: sunday, Stop: sunday.Add(150 * time.Minute)}}
+	now := time.Date(2026, 10, 12, 12, 0, 0, 0, time.Local)
+	l[1].Intervals = []todo.Interval{{Start: now.Add(-30 * time.Minute)}}
+
+	testCases := []struct {
+		by       string
+		expected string
+	}{
+		{"day", "2026-10-11: 1h\n2026-10-12: 2h\nTotal: 3h\n"},
+		{"week", "2026-10-05: 1h\n2026-10-12: 2h\nTotal: 3h\n"},
+		{"tag", "(no tag): 30m\n+work: 2h30m\nTotal: 3h\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.by, func(t *testing.T) {
+			entries, err := l.TimeReport(nil, tc.by, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out := todo.TimeString(entries); out != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, out)
+			}
+		})
+	}
+
+	entries, _ := l.TimeReport(nil, "day", now)
+	var buf bytes.Buffer
+	if err := todo.WriteTimeCSV(&buf, "day", entries); err != nil {
+		t.Fatal(err)
+	}
+	expected := "day,hours\n2026-10-11,1.00\n2026-10-12,2.00\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, buf.String())
+	}
+
+	if _, err := l.TimeReport(nil, "month", now); err == nil {
+		t.Errorf("Expected error for an invalid report")
+	}
+}
+
+// TestTimeTxt tests that tracked time is kept in todo.txt files
+func TestTimeTxt(t *testing.T) {
+	l1 := todo.List{}
+	l1.Add("task")
+	l1.Start(1)
+	l1.Stop()
+	l1.Start(1)
+
+	var buf bytes.Buffer
+	if err := l1.WriteTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	l2 := todo.List{}
+	if err := l2.ReadTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := l1[0].Intervals, l2[0].Intervals
+	if len(a) != len(b) {
+		t.Fatalf("Expected %d intervals, got %d instead", len(a), len(b))
+	}
+	for k := range a {
+		if !a[k].Start.Equal(b[k].Start) || !a[k].Stop.Equal(b[k].Stop) {
+			t.Errorf("Interval %d changed: %+v %+v", k, a[k], b[k])
+		}
+	}
+}
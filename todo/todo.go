@@ -2,157 +2,426 @@ package todo
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 )
 
+// DateLayout is the layout used to read and print due dates
+const DateLayout = "2006-01-02"
+
 // item represents a ToDo item
 type item struct {
+	ID int
+	// removed items only keep the ID of a deleted item, see Delete
+	Removed bool `json:",omitempty"`
 	Task string
 	Done bool
 	CreatedAt time.Time
 	CompletedAt time.Time
+	Priority string `json:",omitempty"`
+	Due time.Time
+	Projects []string `json:",omitempty"`
+	Contexts []string `json:",omitempty"`
+	Recur *Recurrence `json:",omitempty"`
+	// the ID of the next occurrence added when completing the item
+	Next int `json:",omitempty"`
+	Parent int `json:",omitempty"`
+	BlockedBy []int `json:",omitempty"`
+	Notes []Note `json:",omitempty"`
+	Intervals []Interval `json:",omitempty"`
+	DeletedAt *time.Time `json:",omitempty"`
+	SnoozedUntil *time.Time `json:",omitempty"`
 }
 
-// List represents a list of ToDo items
+// List represents a list of ToDo items. Copies of a list share
+// the slices of their items, so methods never change the slices
+// of an item in place but replace them with changed copies
 type List []item
 
-// Add creates a new todo item and appends it to the list
+// Add creates a new todo item and appends it to the list.
+// Every item gets an ID that is kept when other items are deleted
 func (l *List) Add(task string) {
 	tasks := strings.Split(task, "\n")
 	t := make([]item, len(tasks))
+	next := l.nextID()
 
 	for c := 0; c < len(tasks); c++ {
+		projects, contexts := parseTags(tasks[c])
 		t[c] = item{
+			ID: next + c,
 			Task: tasks[c],
 			Done: false,
 			CreatedAt: time.Now(),
 			CompletedAt: time.Time{},
+			Projects: projects,
+			Contexts: contexts,
 		}
 	}
 
 	*l = append(*l, t...)
+	l.keepIDs(next)
 }
 
-// Complete method marks a ToDo item completed by
-// settind Done = True and CompletedAt to current
-// time
-func (l *List) Complete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d doesn't exist", i)
+// Complete method marks the ToDo item with the given ID
+// completed by settind Done = True and CompletedAt to
+// current time. Completing a recurring item adds its
+// next occurrence to the list. Items with open subtasks
+// can't be completed, see CompleteWithSubtasks
+func (l *List) Complete(id int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	if open := l.openSubtasks(id); len(open) > 0 {
+		return fmt.Errorf("%w: item %d has %d subtasks to complete first", ErrOpenSubtasks, id, len(open))
+	}
+
+	t := (*l)[k]
+	(*l)[k].Done = true
+	(*l)[k].CompletedAt = time.Now()
+	// work on the item ends when it's completed
+	if t.started() {
+		l.Stop()
+	}
+
+	// the next occurrence is only added the first time, also when
+	// the item is reopened and completed again
+	if t.Recur != nil && !t.Done && t.Next == 0 {
+		(*l)[k].Next = l.repeat(t, (*l)[k].CompletedAt)
 	}
-	// adjusting index for 0 based index
-	ls[i-1].Done = true
-	ls[i-1].CompletedAt = time.Now()
 
 	return nil
 }
 
-// Delete method deletes a ToDo item from the list
-func (l *List) Delete(i int) error {
-	ls := *l
-	if i <= 0 || i > len(ls) {
-		return fmt.Errorf("Item %d doesn't exist", i)
+// SetPriority sets the priority of a ToDo item. Priorities are
+// single letters from A (highest) to Z, an empty string clears it
+func (l *List) SetPriority(id int, p string) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	p = strings.ToUpper(p)
+	if p != "" && (len(p) != 1 || p[0] < 'A' || p[0] > 'Z') {
+		return fmt.Errorf("Invalid priority %q, expected a letter from A to Z", p)
 	}
 
-	*l = append(ls[:i-1], ls[i:]...)
+	(*l)[k].Priority = p
 	return nil
 }
 
-// Save method encodes the List as JSON and saves it
-// using the provided file name
-func (l *List) Save(filename string) error {
-	js, err := json.Marshal(l)
+// SetDue sets the due date of a ToDo item. A zero time
+// clears the due date
+func (l *List) SetDue(id int, due time.Time) error {
+	k, err := l.visible(id)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, js, 0644)
+	if !due.IsZero() {
+		due = startOfDay(due)
+	}
+
+	(*l)[k].Due = due
+	// a new due date gets its own reminder
+	(*l)[k].SnoozedUntil = nil
+	return nil
 }
 
-// Get method opens the provided file name, decodes
-// the JSON data and parses it into a List
-func (l *List) Get(filename string) error {
-	file, err := os.ReadFile(filename)
+// Edit replaces the text of a ToDo item, updating its projects
+// and contexts. The other fields are changed with SetPriority,
+// SetDue, SetRecurrence, SetParent and Block
+func (l *List) Edit(id int, task string) error {
+	k, err := l.visible(id)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
 		return err
 	}
 
-	if len(file) == 0 {
-		return nil
+	task = strings.TrimSpace(task)
+	if task == "" || strings.Contains(task, "\n") {
+		return fmt.Errorf("Invalid task %q, expected a single non-empty line", task)
 	}
 
-	return json.Unmarshal(file, l)
+	(*l)[k].Task = task
+	(*l)[k].Projects, (*l)[k].Contexts = parseTags(task)
+	return nil
 }
 
-// String prints out a formatted list
-// Implements the fmt.Stringer interface
-func (l *List) String() string {
-	formatted := ""
+// Uncomplete marks a completed ToDo item as pending again by
+// clearing Done and CompletedAt. Completed parents of the item
+// are reopened too, so they don't have open subtasks
+func (l *List) Uncomplete(id int) error {
+	if _, err := l.visible(id); err != nil {
+		return err
+	}
 
-	for k, t := range *l {
-		prefix := "  "
-		if t.Done {
-			prefix = "X "
+	seen := map[int]bool{}
+	for p := id; p != 0 && !seen[p]; p = l.parentOf(p) {
+		seen[p] = true
+		k, err := l.index(p)
+		if err != nil {
+			break
+		}
+		(*l)[k].Done = false
+		(*l)[k].CompletedAt = time.Time{}
+	}
+
+	return nil
+}
+
+// Move moves the ToDo item with the given ID to position pos
+// of the list, counting from 1. Positions count the items shown,
+// leaving out the ones in the trash and the deleted ones
+func (l *List) Move(id, pos int) error {
+	k, err := l.visible(id)
+	if err != nil {
+		return err
+	}
+
+	t := (*l)[k]
+	ls := append((*l)[:k:k], (*l)[k+1:]...)
+
+	// the indexes of the other items shown
+	shown := []int{}
+	for i := range ls {
+		if !ls[i].hidden() {
+			shown = append(shown, i)
 		}
+	}
+	if pos < 1 || pos > len(shown)+1 {
+		return fmt.Errorf("Position %d doesn't exist", pos)
+	}
+
+	i := len(ls)
+	switch {
+	case pos <= len(shown):
+		i = shown[pos-1]
+	case len(shown) > 0:
+		i = shown[len(shown)-1] + 1
+	}
+	*l = append(ls[:i:i], append(List{t}, ls[i:]...)...)
+	return nil
+}
 
-		// adjust the item number k to print numbers
-		// strarting from 1 instead of 0
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, k+1, t.Task)
+// Swap swaps the positions of the ToDo items with the IDs a and b
+func (l *List) Swap(a, b int) error {
+	ka, err := l.visible(a)
+	if err != nil {
+		return err
+	}
+	kb, err := l.visible(b)
+	if err != nil {
+		return err
 	}
 
-	return formatted
+	(*l)[ka], (*l)[kb] = (*l)[kb], (*l)[ka]
+	return nil
 }
 
-// Uncomplete returns tasks which are not completed
-func (l *List) Uncomplete() string {
-	formatted := ""
+// Delete method deletes the ToDo item with the given ID
+// from the list. Its subtasks become top-level items. Its ID
+// isn't given again: when no other item has a higher one, a
+// removed item keeps it
+func (l *List) Delete(id int) error {
+	k, err := l.index(id)
+	if err != nil {
+		return err
+	}
+	l.unlink(id)
+
+	next := l.nextID()
+	ls := *l
+	*l = append(ls[:k], ls[k+1:]...)
+	l.keepIDs(next)
+	return nil
+}
 
+// index returns the position in the list of the item with
+// the given ID
+func (l *List) index(id int) (int, error) {
 	for k, t := range *l {
-		if !t.Done {
-			formatted += fmt.Sprintf(" %d: %s\n", k+1, t.Task)
+		if t.ID == id && id > 0 && !t.Removed {
+			return k, nil
 		}
 	}
 
-	return formatted
+	return -1, fmt.Errorf("Item %d doesn't exist", id)
 }
 
-// Verbose prints a verbose output
-func (l *List) Verbose() string {
-	output := ""
+// nextID returns the ID for the next item added to the list,
+// one more than the highest ID in use or kept by a removed item
+func (l *List) nextID() int {
+	last := 0
+	for _, t := range *l {
+		if t.ID > last {
+			last = t.ID
+		}
+	}
 
-	for k, t := range *l {
-		prefix := "  "
-		if t.Done {
-			prefix = "X "
+	return last + 1
+}
+
+// keepIDs makes sure the IDs below next aren't given again. The
+// list keeps at most one removed item, with the highest ID given
+// when no item has it anymore
+func (l *List) keepIDs(next int) {
+	ls := (*l)[:0]
+	for _, t := range *l {
+		if !t.Removed {
+			ls = append(ls, t)
 		}
-		output += fmt.Sprintf("%sTask #%d detail:\n", prefix, k+1)
-		output += fmt.Sprintf("\tName: %s\n\tCreated At: %s\n", t.Task, t.CreatedAt)
-		if t.Done {
-			output += fmt.Sprintf("\tCompleted At: %s\n", t.CompletedAt)
+	}
+	*l = ls
+
+	if next > l.nextID() {
+		*l = append(*l, item{ID: next - 1, Removed: true})
+	}
+}
+
+// assignIDs gives an ID to the items read from files saved
+// before items had one, see migrateIDs
+func (l *List) assignIDs() {
+	next := l.nextID()
+	for k := range *l {
+		if (*l)[k].ID == 0 {
+			(*l)[k].ID = next
+			next++
 		}
-		output += "\n"
 	}
+}
 
-	return output
+// Save method encodes the List as JSON and saves it
+// using the provided file name. The file is replaced
+// atomically, readers see either the old or the new list
+func (l *List) Save(filename string) error {
+	return l.SaveWithKey(filename, nil)
 }
 
-func (l *List) UncompleteVerbose() string {
-	output := ""
+// SaveWithKey saves the List like Save, encrypted with key
+// when it isn't nil
+func (l *List) SaveWithKey(filename string, key *Key) error {
+	js, err := json.Marshal(envelope{
+		Version: SchemaVersion,
+		Meta:    Meta{Saved: time.Now()},
+		Items:   *l,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeSealed(filename, js, key)
+}
+
+// Get method opens the provided file name, decodes
+// the JSON data and parses it into a List. Files of
+// older versions are migrated, see SchemaVersion.
+// Encrypted files need GetWithKey
+func (l *List) Get(filename string) error {
+	return l.GetWithKey(filename, nil)
+}
+
+// GetWithKey reads the List like Get, decrypting the file
+// with key when it's encrypted
+func (l *List) GetWithKey(filename string, key *Key) error {
+	file, err := readFile(filename, key)
+	if err != nil {
+		return err
+	}
+
+	if len(file) == 0 {
+		return nil
+	}
 
+	file, err = Migrate(file, SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	e := envelope{}
+	if err := json.Unmarshal(file, &e); err != nil {
+		return err
+	}
+	*l = e.Items
+	l.normalize()
+
+	return nil
+}
+
+// normalize fills in the fields missing from items read from
+// older files or from hand-written ones
+func (l *List) normalize() {
+	// items saved before tags were stored keep them in the task text
 	for k, t := range *l {
-		if !t.Done {
-			output += fmt.Sprintf(" Task #%d detail:\n", k+1)
-			output += fmt.Sprintf("\tName: %s\n\tCreated At: %s\n\n", t.Task, t.CreatedAt)
+		if t.Projects == nil && t.Contexts == nil {
+			(*l)[k].Projects, (*l)[k].Contexts = parseTags(t.Task)
 		}
 	}
+	l.assignIDs()
+}
+
+// String prints out a formatted list with the text layout
+// Implements the fmt.Stringer interface
+func (l *List) String() string {
+	var sb strings.Builder
+	l.Render(&sb, Layout{}, nil, ByIndex)
+	return sb.String()
+}
+
+// title returns the task prefixed with its priority and
+// followed by its due date, when they are set
+func (t item) title() string {
+	title := t.Task
+	if t.Priority != "" {
+		title = fmt.Sprintf("(%s) %s", t.Priority, title)
+	}
+	if !t.Due.IsZero() {
+		title += fmt.Sprintf(" (due %s)", t.Due.Format(DateLayout))
+	}
+	if t.Recur != nil {
+		title += fmt.Sprintf(" (repeats %s)", t.Recur)
+	}
+
+	return title
+}
+
+// details returns the verbose lines for the optional fields
+// of an item
+func (t item) details() string {
+	output := ""
+	if t.Priority != "" {
+		output += fmt.Sprintf("\tPriority: %s\n", t.Priority)
+	}
+	if !t.Due.IsZero() {
+		output += fmt.Sprintf("\tDue: %s\n", t.Due.Format(DateLayout))
+	}
+	if t.SnoozedUntil != nil {
+		output += fmt.Sprintf("\tSnoozed Until: %s\n", t.SnoozedUntil.Format(SnoozeLayout))
+	}
+	if t.Recur != nil {
+		output += fmt.Sprintf("\tRepeats: %s\n", t.Recur)
+	}
+	if t.Parent != 0 {
+		output += fmt.Sprintf("\tSubtask Of: #%d\n", t.Parent)
+	}
+	if len(t.BlockedBy) > 0 {
+		ids := []string{}
+		for _, b := range t.BlockedBy {
+			ids = append(ids, fmt.Sprintf("#%d", b))
+		}
+		output += fmt.Sprintf("\tBlocked By: %s\n", strings.Join(ids, ", "))
+	}
+	if tags := t.tags(); len(tags) > 0 {
+		output += fmt.Sprintf("\tTags: %s\n", strings.Join(tags, " "))
+	}
+	if len(t.Intervals) > 0 {
+		output += fmt.Sprintf("\tTime Spent: %s", formatDuration(t.tracked(time.Now())))
+		if t.started() {
+			output += " (running)"
+		}
+		output += "\n"
+	}
+	output += t.notes()
 
 	return output
 }
@@ -1,8 +1,11 @@
 package todo_test
 
 import (
-	"cli_tools/iteracting/todo"
+	"cli_tools/todo"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -99,3 +102,202 @@ func TestSaveGet(t *testing.T) {
 		t.Errorf("Task %q should match %q task", l1[0].Task, l2[0].Task)
 	}
 }
+
+// TestGetOldFormat tests that files saved before items had IDs,
+// priorities and due dates can still be read
+func TestGetOldFormat(t *testing.T) {
+	tf, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	old := `[{"Task":"Old Task","Done":false,"CreatedAt":"2022-06-01T10:00:00Z","CompletedAt":"0001-01-01T00:00:00Z"},` +
+		`{"Task":"Old Task 2","Done":true,"CreatedAt":"2022-06-01T10:00:00Z","CompletedAt":"2022-06-02T10:00:00Z"}]`
+	if _, err := tf.WriteString(old); err != nil {
+		t.Fatal(err)
+	}
+	tf.Close()
+
+	l := todo.List{}
+	if err := l.Get(tf.Name()); err != nil {
+		t.Fatalf("Error getting list from file: %s", err)
+	}
+
+	if l[0].Task != "Old Task" || l[0].Priority != "" || !l[0].Due.IsZero() {
+		t.Errorf("Unexpected item read from old file: %+v", l[0])
+	}
+
+	if l[0].ID != 1 || l[1].ID != 2 {
+		t.Errorf("Expected IDs 1 and 2 for old items, got %d and %d instead", l[0].ID, l[1].ID)
+	}
+}
+
+// TestStableIDs tests that items keep their ID when other
+// items are deleted and that deleted IDs are rejected
+func TestStableIDs(t *testing.T) {
+	l := todo.List{}
+	l.Add("New Task 1\nNew Task 2\nNew Task 3")
+
+	if err := l.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if l[1].ID != 3 || !l[1].Done {
+		t.Errorf("Expected item 3 to be completed, got %+v instead", l[1])
+	}
+	if err := l.Complete(1); err == nil {
+		t.Errorf("Expected error completing deleted item, got nil")
+	}
+
+	l.Add("New Task 4")
+	if l[2].ID != 4 {
+		t.Errorf("Expected new item ID %d, got %d instead", 4, l[2].ID)
+	}
+
+	expected := "  2: New Task 2\nX 3: New Task 3\n  4: New Task 4\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	// IDs are never given twice, even the highest one once every
+	// item is gone and the list is read again
+	for _, id := range []int{2, 3, 4} {
+		if err := l.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out := l.String(); out != "" {
+		t.Errorf("Expected an empty list, got %q instead", out)
+	}
+	if err := l.Complete(4); err == nil {
+		t.Errorf("Expected error completing deleted item, got nil")
+	}
+
+	filename := filepath.Join(t.TempDir(), "todo.json")
+	if err := l.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	l2 := todo.List{}
+	if err := l2.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+	l2.Add("New Task 5")
+	if len(l2) != 1 || l2[0].ID != 5 {
+		t.Errorf("Expected only the new item with ID %d, got %+v instead", 5, l2)
+	}
+}
+
+// TestEdit tests replacing the text of an item
+func TestEdit(t *testing.T) {
+	l := todo.List{}
+	l.Add("call bob +home")
+
+	if err := l.Edit(1, "call alice +work @phone"); err != nil {
+		t.Fatal(err)
+	}
+	if l[0].Task != "call alice +work @phone" {
+		t.Errorf("Expected task %q, got %q instead", "call alice +work @phone", l[0].Task)
+	}
+	if len(l[0].Projects) != 1 || l[0].Projects[0] != "work" || len(l[0].Contexts) != 1 {
+		t.Errorf("Expected tags to be updated, got %v %v", l[0].Projects, l[0].Contexts)
+	}
+
+	if err := l.Edit(1, "two\nlines"); err == nil {
+		t.Errorf("Expected error for a multi-line task")
+	}
+	if err := l.Edit(2, "task"); err == nil {
+		t.Errorf("Expected error for a missing item")
+	}
+}
+
+// TestUncomplete tests reopening a completed item
+func TestUncomplete(t *testing.T) {
+	l := todo.List{}
+	l.Add("parent\nsubtask")
+	if err := l.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.CompleteWithSubtasks(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Uncomplete(2); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range l {
+		if i.Done || !i.CompletedAt.IsZero() {
+			t.Errorf("Expected item %d to be pending, got %+v", i.ID, i)
+		}
+	}
+
+	if err := l.Uncomplete(3); err == nil {
+		t.Errorf("Expected error for a missing item")
+	}
+}
+
+// TestMoveSwap tests reordering the list
+func TestMoveSwap(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3\ntask 4")
+
+	order := func() string {
+		ids := []string{}
+		for _, i := range l {
+			ids = append(ids, strconv.Itoa(i.ID))
+		}
+		return strings.Join(ids, ",")
+	}
+
+	if err := l.Move(4, 1); err != nil {
+		t.Fatal(err)
+	}
+	if o := order(); o != "4,1,2,3" {
+		t.Errorf("Expected order %q, got %q instead", "4,1,2,3", o)
+	}
+	if err := l.Move(4, 4); err != nil {
+		t.Fatal(err)
+	}
+	if o := order(); o != "1,2,3,4" {
+		t.Errorf("Expected order %q, got %q instead", "1,2,3,4", o)
+	}
+	if err := l.Swap(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if o := order(); o != "3,2,1,4" {
+		t.Errorf("Expected order %q, got %q instead", "3,2,1,4", o)
+	}
+
+	if err := l.Move(1, 5); err == nil {
+		t.Errorf("Expected error for an invalid position")
+	}
+	if err := l.Move(5, 1); err == nil {
+		t.Errorf("Expected error for a missing item")
+	}
+	if err := l.Swap(1, 0); err == nil {
+		t.Errorf("Expected error for a missing item")
+	}
+
+	// positions leave out the items in the trash
+	if err := l.Trash(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Move(4, 2); err != nil {
+		t.Fatal(err)
+	}
+	if o := order(); o != "3,2,4,1" {
+		t.Errorf("Expected order %q, got %q instead", "3,2,4,1", o)
+	}
+	if err := l.Move(3, 3); err != nil {
+		t.Fatal(err)
+	}
+	if o := order(); o != "2,4,1,3" {
+		t.Errorf("Expected order %q, got %q instead", "2,4,1,3", o)
+	}
+	if err := l.Move(1, 4); err == nil {
+		t.Errorf("Expected error for a position past the items shown")
+	}
+}
@@ -0,0 +1,425 @@
+package todo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	txtPriority = regexp.MustCompile(`^\([A-Z]\)$`)
+	txtNextID   = regexp.MustCompile(`^# next-id:([0-9]+)$`)
+	txtWord     = regexp.MustCompile(`\S+`)
+)
+
+// txtKeys are the keys of the extensions kept in todo.txt files
+var txtKeys = map[string]bool{
+	"id": true, "next": true, "parent": true, "blocked": true, "time": true, "note": true,
+	"due": true, "rec": true, "pri": true, "created": true, "completed": true,
+	"deleted": true, "snoozed": true,
+}
+
+// WriteTxt writes the list in the todo.txt format, one task per
+// line. Completed tasks start with "x" and their completion date,
+// pending ones with their priority, followed by the creation date
+// and the task. Fields todo.txt has no syntax for are kept as
+// key:value extensions: id:, due:, rec:, pri: for the priority of
+// completed tasks, parent: and blocked: for subtasks and blocking
+// items, next: for the next occurrence added when completing a
+// repeating task, time: for every interval of tracked time as
+// START/STOP, note: for every note with its escaped text, deleted:
+// for items in the trash, snoozed: for snoozed reminders, and
+// created: and completed: with the exact time when it isn't
+// midnight. Words of
+// the task looking like these extensions are escaped with a
+// backslash, so reading the file back is lossless.
+// When deleted items kept their IDs, a "# next-id:N" line comes first
+func (l *List) WriteTxt(w io.Writer) error {
+	for _, t := range *l {
+		if t.Removed {
+			if _, err := fmt.Fprintf(w, "# next-id:%d\n", l.nextID()); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	for _, t := range *l {
+		if t.Removed {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, t.txt()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadTxt parses a todo.txt file and appends its tasks to the
+// list. Tasks without an id: extension, or with an ID already
+// used in the list, get a new ID and the parent: and blocked:
+// links of the file follow the renumbered tasks. Links to IDs
+// not in the file are dropped, they never point to items already
+// in the list. IDs below the one of a "# next-id:N" line aren't
+// given again
+func (l *List) ReadTxt(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	next := l.nextID()
+	items := List{}
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		if m := txtNextID.FindStringSubmatch(s.Text()); m != nil {
+			if id, err := strconv.Atoi(m[1]); err == nil && id > next {
+				next = id
+			}
+			continue
+		}
+
+		items = append(items, parseTxt(s.Text()))
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	used := map[int]bool{}
+	for _, t := range items {
+		if t.ID >= next {
+			next = t.ID + 1
+		}
+	}
+	for _, t := range *l {
+		used[t.ID] = true
+	}
+
+	inFile := map[int]bool{}
+	renumbered := map[int]int{}
+	for k, t := range items {
+		inFile[t.ID] = true
+		if t.ID == 0 || used[t.ID] {
+			if t.ID != 0 {
+				renumbered[t.ID] = next
+			}
+			items[k].ID = next
+			next++
+		}
+		used[items[k].ID] = true
+	}
+	link := func(id int) int {
+		if !inFile[id] {
+			return 0
+		}
+		if id2, ok := renumbered[id]; ok {
+			return id2
+		}
+		return id
+	}
+	for k, t := range items {
+		items[k].Parent = link(t.Parent)
+		items[k].Next = link(t.Next)
+		blockers := []int{}
+		for _, id := range t.BlockedBy {
+			if id = link(id); id != 0 {
+				blockers = append(blockers, id)
+			}
+		}
+		items[k].BlockedBy = nil
+		if len(blockers) > 0 {
+			items[k].BlockedBy = blockers
+		}
+	}
+
+	*l = append(*l, items...)
+	l.normalize()
+	l.keepIDs(next)
+
+	return nil
+}
+
+// txt returns the item as a todo.txt line
+func (t item) txt() string {
+	parts := []string{}
+
+	switch {
+	case t.Done:
+		parts = append(parts, "x")
+		if !t.CompletedAt.IsZero() {
+			parts = append(parts, t.CompletedAt.Format(DateLayout))
+		}
+	case t.Priority != "":
+		parts = append(parts, "("+t.Priority+")")
+	}
+	if !t.CreatedAt.IsZero() {
+		parts = append(parts, t.CreatedAt.Format(DateLayout))
+	}
+	parts = append(parts, escapeTxt(t.Task))
+
+	if t.Done && t.Priority != "" {
+		parts = append(parts, "pri:"+t.Priority)
+	}
+	if !t.Due.IsZero() {
+		parts = append(parts, "due:"+t.Due.Format(DateLayout))
+	}
+	if t.Recur != nil {
+		parts = append(parts, "rec:"+t.Recur.String())
+	}
+	if t.Next != 0 {
+		parts = append(parts, "next:"+strconv.Itoa(t.Next))
+	}
+	if t.Parent != 0 {
+		parts = append(parts, "parent:"+strconv.Itoa(t.Parent))
+	}
+	if len(t.BlockedBy) > 0 {
+		ids := []string{}
+		for _, b := range t.BlockedBy {
+			ids = append(ids, strconv.Itoa(b))
+		}
+		parts = append(parts, "blocked:"+strings.Join(ids, ","))
+	}
+	for _, i := range t.Intervals {
+		parts = append(parts, "time:"+i.Start.Format(time.RFC3339Nano)+"/"+txtTime(i.Stop))
+	}
+	for _, n := range t.Notes {
+		parts = append(parts, "note:"+n.Time.Format(time.RFC3339Nano)+","+url.PathEscape(n.Text))
+	}
+	if t.ID > 0 {
+		parts = append(parts, "id:"+strconv.Itoa(t.ID))
+	}
+	if !t.CreatedAt.Equal(startOfDay(t.CreatedAt)) {
+		parts = append(parts, "created:"+t.CreatedAt.Format(time.RFC3339Nano))
+	}
+	if t.Done && !t.CompletedAt.Equal(startOfDay(t.CompletedAt)) {
+		parts = append(parts, "completed:"+t.CompletedAt.Format(time.RFC3339Nano))
+	}
+	if t.trashed() {
+		parts = append(parts, "deleted:"+t.DeletedAt.Format(time.RFC3339Nano))
+	}
+	if t.SnoozedUntil != nil {
+		parts = append(parts, "snoozed:"+t.SnoozedUntil.Format(time.RFC3339Nano))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseTxt parses a todo.txt line into an item. The task keeps
+// its text byte for byte: the words read as dates, priority and
+// extensions are left out, each with one of the spaces next to it
+func parseTxt(line string) item {
+	t := item{}
+
+	// gaps[k] are the spaces before the word k, the last one those
+	// after the last word
+	pos := txtWord.FindAllStringIndex(line, -1)
+	all := make([]string, len(pos))
+	gaps := make([]string, len(pos)+1)
+	end := 0
+	for k, p := range pos {
+		all[k], gaps[k] = line[p[0]:p[1]], line[end:p[0]]
+		end = p[1]
+	}
+	gaps[len(pos)] = line[end:]
+	words := all
+
+	// completion marker and date, or priority
+	if words[0] == "x" {
+		t.Done = true
+		words = words[1:]
+		if d, ok := parseTxtDate(words); ok {
+			t.CompletedAt = d
+			words = words[1:]
+		}
+	} else if txtPriority.MatchString(words[0]) {
+		t.Priority = words[0][1:2]
+		words = words[1:]
+	}
+
+	// creation date
+	if d, ok := parseTxtDate(words); ok {
+		t.CreatedAt = d
+		words = words[1:]
+	}
+
+	task := make([]string, len(all))
+	for k, w := range all {
+		switch {
+		case k < len(all)-len(words) || t.setTxtExtension(w):
+			// the space written before the word, or after the
+			// first one, isn't part of the task
+			if k > 0 && gaps[k] != "" {
+				gaps[k] = gaps[k][1:]
+			} else if gaps[k+1] != "" {
+				gaps[k+1] = gaps[k+1][1:]
+			}
+		case isTxtKey(w) && strings.HasPrefix(w, `\`):
+			// task text escaped by escapeTxt
+			task[k] = w[1:]
+		default:
+			task[k] = w
+		}
+	}
+
+	var sb strings.Builder
+	for k, w := range task {
+		sb.WriteString(gaps[k])
+		sb.WriteString(w)
+	}
+	sb.WriteString(gaps[len(task)])
+	t.Task = sb.String()
+
+	return t
+}
+
+// isTxtKey reports whether the word has the key:value form of a
+// known extension, leaving out its leading backslashes
+func isTxtKey(word string) bool {
+	word = strings.TrimLeft(word, `\`)
+	k := strings.Index(word, ":")
+	return k > 0 && k < len(word)-1 && txtKeys[word[:k]]
+}
+
+// escapeTxt prefixes the words of a task which would be read back
+// as extensions with a backslash. Words already starting with one
+// get another, parseTxt removes one
+func escapeTxt(task string) string {
+	return txtWord.ReplaceAllStringFunc(task, func(w string) string {
+		if isTxtKey(w) {
+			return `\` + w
+		}
+		return w
+	})
+}
+
+// setTxtExtension sets the field stored in a key:value word and
+// reports whether the word was a known extension. Words with a
+// known key and an invalid value are left as task text, so a task
+// mentioning "due:friday" can still be read
+func (t *item) setTxtExtension(word string) bool {
+	k := strings.Index(word, ":")
+	if k <= 0 || k == len(word)-1 {
+		return false
+	}
+	key, value := word[:k], word[k+1:]
+
+	switch key {
+	case "id":
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			return false
+		}
+		t.ID = id
+	case "next", "parent":
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			return false
+		}
+		if key == "next" {
+			t.Next = id
+		} else {
+			t.Parent = id
+		}
+	case "blocked":
+		ids := []int{}
+		for _, v := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(v)
+			if err != nil || id < 1 {
+				return false
+			}
+			ids = append(ids, id)
+		}
+		t.BlockedBy = append(t.BlockedBy, ids...)
+	case "time":
+		k := strings.Index(value, "/")
+		if k < 0 {
+			return false
+		}
+		start, err := time.Parse(time.RFC3339Nano, value[:k])
+		if err != nil {
+			return false
+		}
+		i := Interval{Start: start}
+		if value[k+1:] != "" {
+			if i.Stop, err = time.Parse(time.RFC3339Nano, value[k+1:]); err != nil {
+				return false
+			}
+		}
+		t.Intervals = append(t.Intervals, i)
+	case "note":
+		k := strings.Index(value, ",")
+		if k < 0 {
+			return false
+		}
+		ts, err := time.Parse(time.RFC3339Nano, value[:k])
+		if err != nil {
+			return false
+		}
+		text, err := url.PathUnescape(value[k+1:])
+		if err != nil {
+			return false
+		}
+		t.Notes = append(t.Notes, Note{Time: ts, Text: text})
+	case "due":
+		d, err := time.ParseInLocation(DateLayout, value, time.Local)
+		if err != nil {
+			return false
+		}
+		t.Due = d
+	case "rec":
+		r, err := ParseRecurrence(value)
+		if err != nil {
+			return false
+		}
+		t.Recur = r
+	case "pri":
+		if !txtPriority.MatchString("(" + value + ")") {
+			return false
+		}
+		t.Priority = value
+	case "created", "completed", "deleted", "snoozed":
+		ts, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "created":
+			t.CreatedAt = ts
+		case "completed":
+			t.CompletedAt = ts
+		case "snoozed":
+			t.SnoozedUntil = &ts
+		default:
+			t.DeletedAt = &ts
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// txtTime formats the time of a time: extension, a zero time is
+// left empty
+func txtTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// parseTxtDate parses the first word as a date, if there is one
+func parseTxtDate(words []string) (time.Time, bool) {
+	if len(words) == 0 {
+		return time.Time{}, false
+	}
+
+	d, err := time.ParseInLocation(DateLayout, words[0], time.Local)
+	return d, err == nil
+}
@@ -0,0 +1,186 @@
+package todo_test
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReadTxt tests parsing todo.txt lines
+func TestReadTxt(t *testing.T) {
+	input := `(A) 2026-01-02 call mom +family @phone due:2026-01-05
+x 2026-01-04 2026-01-01 pay bills pri:B id:7
+
+2026-01-03 water plants rec:weekly:mon,thu see http://example.com
+`
+	l := todo.List{}
+	if err := l.ReadTxt(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "  8: (A) call mom +family @phone (due 2026-01-05)\n" +
+		"X 7: (B) pay bills\n" +
+		"  9: water plants see http://example.com (repeats weekly:mon,thu)\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	if l[1].CompletedAt.Format(todo.DateLayout) != "2026-01-04" ||
+		l[1].CreatedAt.Format(todo.DateLayout) != "2026-01-01" {
+		t.Errorf("Unexpected dates %s, %s", l[1].CompletedAt, l[1].CreatedAt)
+	}
+	if len(l[0].Contexts) != 1 || l[0].Contexts[0] != "phone" {
+		t.Errorf("Expected contexts [phone], got %v instead", l[0].Contexts)
+	}
+
+	var buf bytes.Buffer
+	if err := l.WriteTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected = `(A) 2026-01-02 call mom +family @phone due:2026-01-05 id:8
+x 2026-01-04 2026-01-01 pay bills pri:B id:7
+2026-01-03 water plants see http://example.com rec:weekly:mon,thu id:9
+`
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, buf.String())
+	}
+
+	// invalid extensions are read as task text
+	for _, line := range []string{"task id:x", "task due:tomorrow", "task rec:hourly", "task blocked:1,x"} {
+		l := todo.List{}
+		if err := l.ReadTxt(strings.NewReader(line)); err != nil {
+			t.Fatal(err)
+		}
+		if l[0].Task != line || !l[0].Due.IsZero() || len(l[0].BlockedBy) != 0 {
+			t.Errorf("Expected task %q, got %+v instead", line, l[0])
+		}
+	}
+}
+
+// TestReadTxtLinks tests that links of an imported file follow its
+// renumbered tasks and never point to items already in the list
+func TestReadTxtLinks(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3")
+
+	input := `imported child parent:3 id:7
+blocked task blocked:3,1 id:8
+imported parent id:1
+`
+	if err := l.ReadTxt(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l) != 6 {
+		t.Fatalf("Expected %d items, got %d instead", 6, len(l))
+	}
+	if child := l[3]; child.ID != 7 || child.Parent != 0 {
+		t.Errorf("Expected item 7 without parent, got %+v", child)
+	}
+	if b := l[4].BlockedBy; len(b) != 1 || b[0] != 9 {
+		t.Errorf("Expected item 8 blocked by [9], got %v", b)
+	}
+	if l[5].ID != 9 {
+		t.Errorf("Expected imported item 1 to get ID 9, got %d", l[5].ID)
+	}
+}
+
+// TestTxtEscape tests that task words looking like extensions, and
+// the spaces between words, are kept as task text when writing and
+// reading todo.txt
+func TestTxtEscape(t *testing.T) {
+	tasks := []string{
+		"call mom due:friday",
+		"close ticket id:42",
+		"read due:2026-05-01 notes",
+		`escaped \id:7 and \\note:x,y`,
+		"a  b\tc",
+		" spaces around\tid:3 ",
+	}
+	l1 := todo.List{}
+	l1.Add(strings.Join(tasks, "\n"))
+
+	var buf bytes.Buffer
+	if err := l1.WriteTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), ` \id:42 id:2`) {
+		t.Errorf("Expected the task word id:42 to be escaped, got %q", buf.String())
+	}
+
+	l2 := todo.List{}
+	if err := l2.ReadTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(l2) != len(tasks) {
+		t.Fatalf("Expected %d items, got %d instead", len(tasks), len(l2))
+	}
+	for k, task := range tasks {
+		if it := l2[k]; it.Task != task || it.ID != k+1 || !it.Due.IsZero() || len(it.Notes) != 0 {
+			t.Errorf("Expected item %d %q, got %+v instead", k+1, task, it)
+		}
+	}
+}
+
+// TestTxtLossless tests that writing a list as todo.txt and
+// reading it back keeps every field
+func TestTxtLossless(t *testing.T) {
+	l1 := todo.List{}
+	l1.Add("call bob +work @phone\nrenew cert\nplain task")
+	if err := l1.SetPriority(1, "C"); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := todo.ParseRecurrence("monthly:1")
+	if err := l1.SetRecurrence(2, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.Delete(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.Annotate(2, "see https://example.com/a,b\nsecond line: 100%"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.SetDue(2, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l1.Snooze(2, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := l1.WriteTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	l2 := todo.List{}
+	if err := l2.ReadTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(l1) != len(l2) {
+		t.Fatalf("Expected %d items, got %d instead", len(l1), len(l2))
+	}
+	for k := range l1 {
+		a, b := l1[k], l2[k]
+		if a.ID != b.ID || a.Task != b.Task || a.Done != b.Done || a.Priority != b.Priority ||
+			!a.CreatedAt.Equal(b.CreatedAt) || !a.CompletedAt.Equal(b.CompletedAt) ||
+			!a.Due.Equal(b.Due) || (a.Recur == nil) != (b.Recur == nil) {
+			t.Errorf("Item %d changed:\n%+v\n%+v", k, a, b)
+		}
+		if len(a.Notes) != len(b.Notes) {
+			t.Fatalf("Expected %d notes, got %d instead", len(a.Notes), len(b.Notes))
+		}
+		for n := range a.Notes {
+			if a.Notes[n].Text != b.Notes[n].Text || !a.Notes[n].Time.Equal(b.Notes[n].Time) {
+				t.Errorf("Note %d changed: %+v %+v", n, a.Notes[n], b.Notes[n])
+			}
+		}
+		if (a.SnoozedUntil == nil) != (b.SnoozedUntil == nil) || a.SnoozedUntil != nil && !a.SnoozedUntil.Equal(*b.SnoozedUntil) {
+			t.Errorf("Snooze of item %d changed: %v %v", k, a.SnoozedUntil, b.SnoozedUntil)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package todo
+
+import (
+	"fmt"
+	"time"
+)
+
+// Trash moves the item id to the trash. Items in the trash are
+// kept in the list, with their ID, but hidden from every view and
+// left unchanged until they are restored or the trash is emptied
+func (l *List) Trash(id int) error {
+	k, err := l.index(id)
+	if err != nil {
+		return err
+	}
+	if (*l)[k].trashed() {
+		return fmt.Errorf("Item %d is already in the trash", id)
+	}
+
+	if (*l)[k].started() {
+		l.Stop()
+	}
+	now := time.Now()
+	(*l)[k].DeletedAt = &now
+	return nil
+}
+
+// Restore takes the item id out of the trash
+func (l *List) Restore(id int) error {
+	k, err := l.index(id)
+	if err != nil {
+		return err
+	}
+	if !(*l)[k].trashed() {
+		return fmt.Errorf("Item %d isn't in the trash", id)
+	}
+
+	(*l)[k].DeletedAt = nil
+	return nil
+}
+
+// EmptyTrash deletes the items in the trash for good and returns
+// how many were deleted
+func (l *List) EmptyTrash() int {
+	ids := []int{}
+	for _, t := range *l {
+		if t.trashed() {
+			ids = append(ids, t.ID)
+		}
+	}
+
+	for _, id := range ids {
+		l.Delete(id)
+	}
+	return len(ids)
+}
+
+// Trashed returns the items in the trash as a list of their own,
+// so they can be shown with the usual views
+func (l *List) Trashed() List {
+	trash := List{}
+	for _, t := range *l {
+		if t.trashed() {
+			t.DeletedAt = nil
+			trash = append(trash, t)
+		}
+	}
+	return trash
+}
+
+// visible returns the position in the list of the item id like
+// index, but fails for items in the trash, which can only be
+// restored or deleted
+func (l *List) visible(id int) (int, error) {
+	k, err := l.index(id)
+	if err != nil {
+		return -1, err
+	}
+	if (*l)[k].trashed() {
+		return -1, fmt.Errorf("Item %d is in the trash", id)
+	}
+	return k, nil
+}
+
+func (t item) trashed() bool {
+	return t.DeletedAt != nil
+}
+
+// hidden reports whether the item is left out of the views: it's
+// in the trash or only keeps the ID of a deleted item
+func (t item) hidden() bool {
+	return t.Removed || t.trashed()
+}
@@ -0,0 +1,133 @@
+package todo_test
+
+import (
+	"bytes"
+	"cli_tools/todo"
+	"testing"
+	"time"
+)
+
+// TestTrash tests moving items to the trash and back
+func TestTrash(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3")
+
+	if err := l.Trash(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Trash(2); err == nil {
+		t.Errorf("Expected error trashing an item twice")
+	}
+
+	expected := "  1: task 1\n  3: task 3\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+	trash := l.Trashed()
+	if out := trash.String(); out != "  2: task 2\n" {
+		t.Errorf("Expected the trash to hold item 2, got %q", out)
+	}
+
+	// new items don't reuse the IDs of the items in the trash
+	l.Add("task 4")
+	if l[3].ID != 4 {
+		t.Errorf("Expected ID %d, got %d instead", 4, l[3].ID)
+	}
+
+	if err := l.Restore(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Restore(2); err == nil {
+		t.Errorf("Expected error restoring an item not in the trash")
+	}
+	expected = "  1: task 1\n  2: task 2\n  3: task 3\n  4: task 4\n"
+	if out := l.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	l.Trash(1)
+	l.Trash(3)
+	if n := l.EmptyTrash(); n != 2 {
+		t.Errorf("Expected %d items deleted, got %d instead", 2, n)
+	}
+	if len(l) != 2 {
+		t.Errorf("Expected %d items left, got %d instead", 2, len(l))
+	}
+}
+
+// TestTrashSubtask tests that subtasks in the trash don't keep
+// their parent from being completed
+func TestTrashSubtask(t *testing.T) {
+	l := todo.List{}
+	l.Add("parent\nsubtask")
+	if err := l.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Trash(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Complete(1); err != nil {
+		t.Errorf("Expected parent to be completed, got %v", err)
+	}
+}
+
+// TestTrashedItems tests that items in the trash can't be changed
+// until they are restored
+func TestTrashedItems(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2")
+	if err := l.Trash(2); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := map[string]func() error{
+		"Complete":  func() error { return l.Complete(2) },
+		"Edit":      func() error { return l.Edit(2, "changed") },
+		"SetDue":    func() error { return l.SetDue(2, time.Now()) },
+		"SetParent": func() error { return l.SetParent(2, 1) },
+		"Parent":    func() error { return l.SetParent(1, 2) },
+		"Block":     func() error { return l.Block(1, 2) },
+		"Move":      func() error { return l.Move(2, 1) },
+		"Annotate":  func() error { return l.Annotate(2, "note") },
+	}
+	for name, change := range changes {
+		if err := change(); err == nil {
+			t.Errorf("%s: expected error changing an item in the trash, got nil", name)
+		}
+	}
+	trash := l.Trashed()
+	if out := trash.String(); out != "  2: task 2\n" {
+		t.Errorf("Expected item 2 unchanged in the trash, got %q", out)
+	}
+
+	if err := l.Restore(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Complete(2); err != nil {
+		t.Errorf("Expected restored item to be completed, got %v", err)
+	}
+}
+
+// TestTrashTxt tests that the trash is kept in todo.txt files
+func TestTrashTxt(t *testing.T) {
+	l1 := todo.List{}
+	l1.Add("task 1\ntask 2")
+	l1.Trash(1)
+
+	var buf bytes.Buffer
+	if err := l1.WriteTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+	l2 := todo.List{}
+	if err := l2.ReadTxt(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if l2[0].DeletedAt == nil || !l2[0].DeletedAt.Equal(*l1[0].DeletedAt) {
+		t.Errorf("Expected item 1 to stay in the trash, got %+v", l2[0])
+	}
+	if l2[1].DeletedAt != nil {
+		t.Errorf("Expected item 2 not to be in the trash")
+	}
+}
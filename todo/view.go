@@ -0,0 +1,157 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter reports whether an item should be included in a view
+type Filter func(t item) bool
+
+// Order defines how the items of a view are sorted
+type Order int
+
+const (
+	// ByIndex keeps the items in the order they were added
+	ByIndex Order = iota
+	// ByPriority sorts items from priority A to Z, items without
+	// priority come last
+	ByPriority
+	// ByDue sorts items by the closest due date, items without
+	// due date come last
+	ByDue
+)
+
+// ParseOrder converts the name of a sort order into an Order
+func ParseOrder(name string) (Order, error) {
+	switch name {
+	case "", "index":
+		return ByIndex, nil
+	case "priority":
+		return ByPriority, nil
+	case "due":
+		return ByDue, nil
+	}
+
+	return ByIndex, fmt.Errorf("Invalid sort order %q", name)
+}
+
+// Pending keeps the items which are not completed
+func Pending(t item) bool {
+	return !t.Done
+}
+
+// Overdue keeps the pending items whose due date is before
+// the day of now
+func Overdue(now time.Time) Filter {
+	today := startOfDay(now)
+	return func(t item) bool {
+		return !t.Done && !t.Due.IsZero() && t.Due.Before(today)
+	}
+}
+
+// DueThisWeek keeps the pending items due between the day of
+// now and the end of the week (weeks start on Monday)
+func DueThisWeek(now time.Time) Filter {
+	today := startOfDay(now)
+	daysLeft := 7 - (int(now.Weekday())+6)%7
+	end := today.AddDate(0, 0, daysLeft)
+
+	return func(t item) bool {
+		return !t.Done && !t.Due.IsZero() &&
+			!t.Due.Before(today) && t.Due.Before(end)
+	}
+}
+
+// All combines filters, keeping the items accepted by every
+// one of them. Nil filters are ignored
+func All(filters ...Filter) Filter {
+	return func(t item) bool {
+		for _, f := range filters {
+			if f != nil && !f(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// view returns the indexes of the items accepted by the filter
+// sorted by the given order. A nil filter keeps every item but
+// the ones in the trash
+func (l *List) view(f Filter, o Order) []int {
+	idx := []int{}
+	for k, t := range *l {
+		if !t.hidden() && (f == nil || f(t)) {
+			idx = append(idx, k)
+		}
+	}
+
+	ls := *l
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := ls[idx[i]], ls[idx[j]]
+		switch o {
+		case ByPriority:
+			if a.Priority != b.Priority {
+				return lessPriority(a.Priority, b.Priority)
+			}
+			return lessDue(a.Due, b.Due)
+		case ByDue:
+			if !a.Due.Equal(b.Due) {
+				return lessDue(a.Due, b.Due)
+			}
+			return lessPriority(a.Priority, b.Priority)
+		}
+		return false
+	})
+
+	return idx
+}
+
+// Lines returns the IDs of the items accepted by the filter in
+// the given order, along with the line of the text layout of each
+func (l *List) Lines(f Filter, o Order) ([]int, []string) {
+	ids := []int{}
+	lines := []string{}
+
+	order, depth := l.tree(l.view(f, o))
+	for c, k := range order {
+		t := (*l)[k]
+		prefix := "  "
+		if t.Done {
+			prefix = "X "
+		}
+		prefix += strings.Repeat("  ", depth[c])
+
+		ids = append(ids, t.ID)
+		lines = append(lines, fmt.Sprintf("%s%d: %s%s", prefix, t.ID, t.title(), l.blockedSuffix(t)))
+	}
+
+	return ids, lines
+}
+
+// lessPriority reports whether priority a comes before b,
+// an empty priority comes after any letter
+func lessPriority(a, b string) bool {
+	if a == "" || b == "" {
+		return a != ""
+	}
+	return a < b
+}
+
+// lessDue reports whether due date a comes before b,
+// a missing due date comes after any date
+func lessDue(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return !a.IsZero()
+	}
+	return a.Before(b)
+}
+
+// startOfDay returns the midnight which starts the day of t
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
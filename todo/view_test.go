@@ -0,0 +1,117 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"testing"
+	"time"
+)
+
+// TestStringViewOrder tests sorting the list by priority and due date
+func TestStringViewOrder(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3")
+
+	if err := l.SetPriority(2, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetPriority(3, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDue(1, time.Date(2026, 3, 1, 15, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDue(3, time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		order    todo.Order
+		expected string
+	}{
+		{"Index", todo.ByIndex, "  1: task 1 (due 2026-03-01)\n  2: (B) task 2\n  3: (A) task 3 (due 2026-04-01)\n"},
+		{"Priority", todo.ByPriority, "  3: (A) task 3 (due 2026-04-01)\n  2: (B) task 2\n  1: task 1 (due 2026-03-01)\n"},
+		{"Due", todo.ByDue, "  1: task 1 (due 2026-03-01)\n  3: (A) task 3 (due 2026-04-01)\n  2: (B) task 2\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := render(t, &l, "text", nil, tc.order); out != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, out)
+			}
+		})
+	}
+}
+
+// TestSetPriorityInvalid tests that invalid priorities are rejected
+func TestSetPriorityInvalid(t *testing.T) {
+	l := todo.List{}
+	l.Add("task")
+
+	for _, p := range []string{"AB", "1", "-"} {
+		if err := l.SetPriority(1, p); err == nil {
+			t.Errorf("Expected error for priority %q, got nil", p)
+		}
+	}
+	if err := l.SetPriority(2, "A"); err == nil {
+		t.Errorf("Expected error for missing item, got nil")
+	}
+}
+
+// TestDueFilters tests the overdue and due this week filters
+func TestDueFilters(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local)
+
+	l := todo.List{}
+	l.Add("yesterday\ntoday\nsunday\nnext monday\nno due\ndone yesterday")
+	dues := map[int]time.Time{
+		1: now.AddDate(0, 0, -1),
+		2: now,
+		3: now.AddDate(0, 0, 4),
+		4: now.AddDate(0, 0, 5),
+		6: now.AddDate(0, 0, -1),
+	}
+	for i, d := range dues {
+		if err := l.SetDue(i, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Complete(6); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "  1: yesterday (due 2026-10-13)\n"
+	if out := render(t, &l, "text", todo.Overdue(now), todo.ByIndex); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+
+	expected = "  2: today (due 2026-10-14)\n  3: sunday (due 2026-10-18)\n"
+	if out := render(t, &l, "text", todo.DueThisWeek(now), todo.ByIndex); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+}
+
+// TestLines tests that Lines returns the IDs along with the lines
+// printed by StringView
+func TestLines(t *testing.T) {
+	l := todo.List{}
+	l.Add("task 1\ntask 2\ntask 3")
+	if err := l.SetParent(3, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, lines := l.Lines(nil, todo.ByIndex)
+	expectedIDs := []int{1, 3, 2}
+	for k, id := range expectedIDs {
+		if ids[k] != id {
+			t.Errorf("Expected ID %d at line %d, got %d instead", id, k, ids[k])
+		}
+	}
+	if lines[1] != "    3: task 3" {
+		t.Errorf("Expected subtask line %q, got %q instead", "    3: task 3", lines[1])
+	}
+	if s := render(t, &l, "text", nil, todo.ByIndex); s != lines[0]+"\n"+lines[1]+"\n"+lines[2]+"\n" {
+		t.Errorf("Expected StringView to print the lines, got %q", s)
+	}
+}
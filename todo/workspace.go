@@ -0,0 +1,241 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultList is the name of the list kept in the main ToDo file
+const DefaultList = "default"
+
+// listName matches the names allowed for lists
+var listName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Workspace is a set of named lists: the default list, kept in the
+// main ToDo file, and the others kept in a directory, one file per
+// list named after it
+type Workspace struct {
+	filename string
+	dir      string
+	format   string
+	key      *Key
+}
+
+// NewWorkspace returns the workspace of the main ToDo file filename,
+// with the named lists kept in dir using the given storage format,
+// encrypted with key when it isn't nil
+func NewWorkspace(filename, dir, format string, key *Key) *Workspace {
+	return &Workspace{filename: filename, dir: dir, format: format, key: key}
+}
+
+// File returns the file keeping the list name
+func (w *Workspace) File(name string) (string, error) {
+	if name == DefaultList {
+		return w.filename, nil
+	}
+	if !listName.MatchString(name) {
+		return "", fmt.Errorf("Invalid list name %q, use letters, digits, - and _", name)
+	}
+
+	return filepath.Join(w.dir, name+w.ext()), nil
+}
+
+// Exists reports whether the list name exists. The default list
+// always exists
+func (w *Workspace) Exists(name string) bool {
+	if name == DefaultList {
+		return true
+	}
+	file, err := w.File(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(file)
+	return err == nil
+}
+
+// Names returns the names of the lists, the default one first and
+// the others sorted
+func (w *Workspace) Names() ([]string, error) {
+	names := []string{DefaultList}
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return names, nil
+		}
+		return nil, err
+	}
+
+	others := []string{}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), w.ext())
+		// archives, journals and locks don't match the list names
+		if !e.IsDir() && name != e.Name() && name != DefaultList && listName.MatchString(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+
+	return append(names, others...), nil
+}
+
+// Create creates the empty list name
+func (w *Workspace) Create(name string) error {
+	file, err := w.File(name)
+	if err != nil {
+		return err
+	}
+	if w.Exists(name) {
+		return fmt.Errorf("List %s already exists", name)
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+
+	store, err := NewStoreWithKey(file, w.format, w.key)
+	if err != nil {
+		return err
+	}
+	return store.Save(&List{})
+}
+
+// Rename renames the list old to new, along with its journal and
+// archive
+func (w *Workspace) Rename(old, new string) error {
+	if old == DefaultList || new == DefaultList {
+		return fmt.Errorf("The %s list can't be renamed", DefaultList)
+	}
+	from, err := w.File(old)
+	if err != nil {
+		return err
+	}
+	to, err := w.File(new)
+	if err != nil {
+		return err
+	}
+
+	// both lists are locked, in the same order as other processes
+	// do, before checking the new name is free
+	files := []string{from, to}
+	switch {
+	case to == from:
+		files = files[:1]
+	case to < from:
+		files = []string{to, from}
+	}
+	for _, f := range files {
+		lock, err := LockFile(f)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	if !w.Exists(old) {
+		return fmt.Errorf("List %s doesn't exist", old)
+	}
+	if w.Exists(new) {
+		return fmt.Errorf("List %s already exists", new)
+	}
+
+	renames := [][2]string{
+		{from, to},
+		{from + ".journal", to + ".journal"},
+		{ArchiveFile(from), ArchiveFile(to)},
+	}
+	for _, r := range renames {
+		if err := os.Rename(r[0], r[1]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes the list name and its journal. Only empty lists
+// without archived items can be deleted
+func (w *Workspace) Delete(name string) error {
+	if name == DefaultList {
+		return fmt.Errorf("The %s list can't be deleted", DefaultList)
+	}
+	file, err := w.File(name)
+	if err != nil {
+		return err
+	}
+	if !w.Exists(name) {
+		return fmt.Errorf("List %s doesn't exist", name)
+	}
+
+	lock, err := LockFile(file)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	store, err := NewStoreWithKey(file, w.format, w.key)
+	if err != nil {
+		return err
+	}
+	l := &List{}
+	if err := store.Load(l); err != nil {
+		return err
+	}
+	for _, t := range *l {
+		if !t.Removed {
+			return fmt.Errorf("List %s isn't empty", name)
+		}
+	}
+	if _, err := os.Stat(ArchiveFile(file)); err == nil {
+		return fmt.Errorf("List %s has archived items", name)
+	}
+
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+	if err := os.Remove(file + ".journal"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ext returns the extension of the list files, the one of the main
+// file when no format is given
+func (w *Workspace) ext() string {
+	switch w.format {
+	case FormatTxt:
+		return ".txt"
+	case FormatDB:
+		return ".db"
+	case "":
+		if ext := filepath.Ext(w.filename); ext == ".txt" || ext == ".db" {
+			return ext
+		}
+	}
+	return ".json"
+}
+
+// MoveTo moves the item id to the list dst, where it gets a new ID,
+// and returns the new ID. Its subtasks stay in l as top-level items,
+// and links to other items are dropped as they don't cross lists
+func (l *List) MoveTo(id int, dst *List) (int, error) {
+	k, err := l.visible(id)
+	if err != nil {
+		return 0, err
+	}
+
+	t := (*l)[k]
+	t.ID = dst.nextID()
+	t.Parent = 0
+	t.BlockedBy = nil
+	t.Next = 0
+	*dst = append(*dst, t)
+	dst.keepIDs(t.ID)
+
+	return t.ID, l.Delete(id)
+}
@@ -0,0 +1,118 @@
+package todo_test
+
+import (
+	"cli_tools/todo"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestWorkspace tests creating, renaming and deleting lists
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	ws := todo.NewWorkspace(filepath.Join(dir, "todo.json"), filepath.Join(dir, "lists"), "", nil)
+
+	for _, name := range []string{"work", "home"} {
+		if err := ws.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ws.Create("work"); err == nil {
+		t.Error("Expected an error creating an existing list")
+	}
+	if err := ws.Create("../escape"); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+
+	names, err := ws.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"default", "home", "work"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v instead", expected, names)
+	}
+
+	if err := ws.Rename("home", "house"); err != nil {
+		t.Fatal(err)
+	}
+	if ws.Exists("home") || !ws.Exists("house") {
+		t.Error("Expected the list to be renamed")
+	}
+	if err := ws.Rename("house", "work"); err == nil {
+		t.Error("Expected an error renaming onto an existing list")
+	}
+	if err := ws.Rename("house", "house"); err == nil {
+		t.Error("Expected an error renaming a list to its own name")
+	}
+	if err := ws.Rename("garden", "yard"); err == nil {
+		t.Error("Expected an error renaming a missing list")
+	}
+
+	// lists with items can't be deleted
+	file, err := ws.File("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := todo.List{}
+	l.Add("task")
+	if err := l.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Delete("work"); err == nil {
+		t.Error("Expected an error deleting a list with items")
+	}
+	// deleted items only keep their IDs
+	if err := l.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Delete("work"); err != nil {
+		t.Errorf("Expected a list without items to be deleted, got %v", err)
+	}
+	if err := ws.Delete("house"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lists", "house.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the list file to be removed, got %v", err)
+	}
+	if err := ws.Delete(todo.DefaultList); err == nil {
+		t.Error("Expected an error deleting the default list")
+	}
+}
+
+// TestMoveTo tests moving an item to another list
+func TestMoveTo(t *testing.T) {
+	src := todo.List{}
+	src.Add("parent\nchild\nblocker")
+	if err := src.SetParent(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Block(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	dst := todo.List{}
+	dst.Add("other")
+
+	id, err := src.MoveTo(1, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 {
+		t.Errorf("Expected new ID %d, got %d instead", 2, id)
+	}
+
+	expected := "  2: child\n  3: blocker\n"
+	if out := src.String(); out != expected {
+		t.Errorf("Expected %q, got %q instead", expected, out)
+	}
+	if dst[1].Task != "parent" || dst[1].Parent != 0 || len(dst[1].BlockedBy) != 0 {
+		t.Errorf("Expected the moved item without links, got %+v", dst[1])
+	}
+
+	if _, err := src.MoveTo(1, &dst); err == nil {
+		t.Error("Expected an error moving a missing item")
+	}
+}